@@ -7,14 +7,21 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/BloggingApp/notification-service/internal/config"
 	"github.com/BloggingApp/notification-service/internal/handler"
 	"github.com/BloggingApp/notification-service/internal/mailer"
+	"github.com/BloggingApp/notification-service/internal/mobilepush"
+	"github.com/BloggingApp/notification-service/internal/netguard"
 	"github.com/BloggingApp/notification-service/internal/rabbitmq"
 	"github.com/BloggingApp/notification-service/internal/repository"
 	"github.com/BloggingApp/notification-service/internal/repository/postgres"
 	"github.com/BloggingApp/notification-service/internal/service"
+	"github.com/BloggingApp/notification-service/internal/sms"
+	"github.com/BloggingApp/notification-service/internal/unsubscribe"
+	"github.com/BloggingApp/notification-service/internal/webpush"
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
@@ -37,7 +44,11 @@ func main() {
 		log.Fatalf("failed to create zap logger: %s", err.Error())
 	}
 
-	rabbitmq, err := rabbitmq.New(os.Getenv("RABBITMQ_CONN_STRING"))
+	rabbitmq, err := rabbitmq.New(logger, os.Getenv("RABBITMQ_CONN_STRING"), config.RabbitMQConfig{
+		ReconnectInitialDelay: viper.GetDuration("rabbitmq.reconnect.initial_delay"),
+		ReconnectMaxDelay: viper.GetDuration("rabbitmq.reconnect.max_delay"),
+		PublishTimeout: viper.GetDuration("rabbitmq.publish_timeout"),
+	})
 	if err != nil {
 		log.Fatalf("failed to connect to rabbitmq: %s", err.Error())
 	}
@@ -67,19 +78,145 @@ func main() {
 	}
 	log.Printf("Successfully connected to Redis: %s\n", pong)
 
+	unsubscribeSigner, err := unsubscribe.NewSigner([]byte(os.Getenv("UNSUBSCRIBE_SECRET")), viper.GetDuration("unsubscribe.token_ttl"))
+	if err != nil {
+		log.Fatalf("failed to create unsubscribe signer: %s", err.Error())
+	}
+
 	repo := repository.New(db)
-	services := service.New(logger, repo, rdb, rabbitmq)
-	handlers := handler.New(services)
 
-	mailer := mailer.New(logger, rabbitmq)
+	mailerConfig := config.MailerConfig{
+		Transport: viper.GetString("mailer.transport"),
+		From: os.Getenv("FROM"),
+		FromName: viper.GetString("mailer.from_name"),
+		AppName: viper.GetString("mailer.app_name"),
+		PublicURL: viper.GetString("app.public_url"),
+		UnsubscribeURL: viper.GetString("unsubscribe.url"),
+		SMTP: config.SMTPConfig{
+			Username: os.Getenv("FROM"),
+			Password: os.Getenv("PASS"),
+			Host: os.Getenv("HOST"),
+			Port: os.Getenv("PORT"),
+			TLSMode: viper.GetString("mailer.smtp.tls"),
+			MaxConnections: viper.GetInt("mailer.smtp.max_connections"),
+			Timeout: viper.GetDuration("mailer.smtp.timeout"),
+		},
+		FileDir: viper.GetString("mailer.file_dir"),
+		RetryMaxAttempts: viper.GetInt("mailer.retry.max_attempts"),
+		RetryInitialDelay: viper.GetDuration("mailer.retry.initial_delay"),
+		Workers: viper.GetInt("mailer.workers"),
+		Prefetch: viper.GetInt("mailer.prefetch"),
+		RateLimit: viper.GetFloat64("mailer.rate_limit"),
+		RateBurst: viper.GetInt("mailer.rate_burst"),
+		RecipientLimit: config.MailLimit{
+			Max: viper.GetInt("mailer.limits.recipient.max"),
+			Window: viper.GetDuration("mailer.limits.recipient.window"),
+		},
+		GlobalLimit: config.MailLimit{
+			Max: viper.GetInt("mailer.limits.global.max"),
+			Window: viper.GetDuration("mailer.limits.global.window"),
+		},
+	}
+	if err := viper.UnmarshalKey("mailer.limits.types", &mailerConfig.TypeLimits); err != nil {
+		log.Fatalf("failed to read mail type limits: %s", err.Error())
+	}
+	if err := viper.UnmarshalKey("mailer.dkim", &mailerConfig.DKIM, viper.DecodeHook(mapstructure.StringToTimeHookFunc(time.RFC3339))); err != nil {
+		log.Fatalf("failed to read dkim config: %s", err.Error())
+	}
+	mailTransport, err := mailer.NewTransport(logger, mailerConfig)
+	if err != nil {
+		log.Fatalf("failed to create mail transport: %s", err.Error())
+	}
+
+	mailer := mailer.New(logger, rabbitmq, rdb, mailTransport, repo, unsubscribeSigner, mailerConfig)
 	mailer.StartProcessing()
 
+	smsConfig := config.SMSConfig{
+		Provider: viper.GetString("sms.provider"),
+		AppName: viper.GetString("mailer.app_name"),
+		HTTP: config.SMSHTTPConfig{
+			URL: viper.GetString("sms.http.url"),
+			Token: os.Getenv("SMS_GATEWAY_TOKEN"),
+			From: viper.GetString("sms.http.from"),
+			Timeout: viper.GetDuration("sms.http.timeout"),
+		},
+		StatusCallbackURL: viper.GetString("sms.status_callback_url"),
+		RetryMaxAttempts: viper.GetInt("sms.retry.max_attempts"),
+		RetryInitialDelay: viper.GetDuration("sms.retry.initial_delay"),
+		Workers: viper.GetInt("sms.workers"),
+		NumberLimit: config.MailLimit{
+			Max: viper.GetInt("sms.limits.number.max"),
+			Window: viper.GetDuration("sms.limits.number.window"),
+		},
+	}
+	smsCallbackToken := os.Getenv("SMS_CALLBACK_TOKEN")
+	smsProvider, err := sms.NewProvider(logger, smsConfig, smsCallbackToken)
+	if err != nil {
+		log.Fatalf("failed to create sms provider: %s", err.Error())
+	}
+
+	smsSender := sms.New(logger, rabbitmq, rdb, smsProvider, repo, smsCallbackToken, smsConfig)
+	smsSender.StartProcessing()
+
+	var webPush *webpush.Client
+	if vapidPrivateKey := os.Getenv("VAPID_PRIVATE_KEY"); vapidPrivateKey != "" {
+		vapid, err := webpush.NewVAPID(vapidPrivateKey, viper.GetString("webpush.subject"))
+		if err != nil {
+			log.Fatalf("failed to load vapid keys: %s", err.Error())
+		}
+		webPush = webpush.NewClient(netguard.NewHTTPClient(viper.GetDuration("webpush.timeout")), vapid)
+	}
+
+	mobilePushClient := &http.Client{Timeout: viper.GetDuration("mobilepush.timeout")}
+	mobilePush := map[string]mobilepush.Provider{}
+	if credentialsFile := os.Getenv("FCM_CREDENTIALS_FILE"); credentialsFile != "" {
+		fcm, err := mobilepush.NewFCMProvider(mobilePushClient, mobilepush.FCMConfig{
+			BaseURL: viper.GetString("mobilepush.fcm.base_url"),
+			TokenURL: viper.GetString("mobilepush.fcm.token_url"),
+			ProjectID: viper.GetString("mobilepush.fcm.project_id"),
+			CredentialsFile: credentialsFile,
+		})
+		if err != nil {
+			log.Fatalf("failed to create fcm provider: %s", err.Error())
+		}
+		mobilePush[mobilepush.PLATFORM_ANDROID] = fcm
+	}
+	if keyFile := os.Getenv("APNS_KEY_FILE"); keyFile != "" {
+		apns, err := mobilepush.NewAPNsProvider(mobilePushClient, mobilepush.APNsConfig{
+			BaseURL: viper.GetString("mobilepush.apns.base_url"),
+			KeyFile: keyFile,
+			KeyID: viper.GetString("mobilepush.apns.key_id"),
+			TeamID: viper.GetString("mobilepush.apns.team_id"),
+			Topic: viper.GetString("mobilepush.apns.topic"),
+		})
+		if err != nil {
+			log.Fatalf("failed to create apns provider: %s", err.Error())
+		}
+		mobilePush[mobilepush.PLATFORM_IOS] = apns
+	}
+
+	webhookConfig := config.WebhookConfig{
+		Timeout: viper.GetDuration("webhooks.timeout"),
+		RetryMaxAttempts: viper.GetInt("webhooks.retry.max_attempts"),
+		RetryInitialDelay: viper.GetDuration("webhooks.retry.initial_delay"),
+		Workers: viper.GetInt("webhooks.workers"),
+		MaxFailures: viper.GetInt("webhooks.max_failures"),
+	}
+
+	services := service.New(logger, repo, rdb, rabbitmq, unsubscribeSigner, mailer, smsSender, webPush, mobilePush, webhookConfig)
+	handlers := handler.New(services)
+
 	go services.User.StartCreating(ctx)
 	go services.User.StartUpdating(ctx)
 	go services.User.StartCreatingFollowers(ctx)
 	go services.User.StartUpdatingFollowersNewPostNotificationsEnabled(ctx)
 	go services.Notification.StartProcessingNewPostNotifications(ctx)
 	go services.Notification.StartProcessingPostValidationStatusUpdates(ctx)
+	go services.Notification.StartBroadcastingGlobalNotifications(ctx)
+	go services.Notification.StartReceivingForwardedDeliveries(ctx)
+	go services.Webhook.StartProcessingDeliveries(ctx)
+	go services.Notification.StartProcessingPushDeliveries(ctx)
+	go services.Notification.StartPresenceHeartbeats(ctx)
 
 	go services.Notification.StartJobs()
 
@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 type DBConfig struct {
 	Username string
 	Password string
@@ -8,3 +10,93 @@ type DBConfig struct {
 	DBName   string
 	SSLMode  string
 }
+
+type MailerConfig struct {
+	Transport      string // smtp, file, memory or log
+	From           string
+	FromName       string
+	AppName        string
+	PublicURL      string // web app's URL used in links
+	UnsubscribeURL string // this service's one-click unsubscribe endpoint
+	SMTP           SMTPConfig
+	FileDir        string
+	DKIM           DKIMConfig
+
+	RetryMaxAttempts  int
+	RetryInitialDelay time.Duration
+
+	Workers   int     // consumers per queue
+	Prefetch  int     // unacked messages per queue
+	RateLimit float64 // mails per second, 0 means unlimited
+	RateBurst int
+
+	// shared by all replicas through Redis
+	RecipientLimit MailLimit            // mails of any type to one address
+	TypeLimits     map[string]MailLimit // mails of one type to one address, by email type name
+	GlobalLimit    MailLimit
+}
+
+// MailLimit allows Max mails per fixed Window, 0 means unlimited
+type MailLimit struct {
+	Max    int
+	Window time.Duration
+}
+
+type SMTPConfig struct {
+	Username       string
+	Password       string
+	Host           string
+	Port           string
+	TLSMode        string // starttls, implicit or none
+	MaxConnections int
+	Timeout        time.Duration
+}
+
+// DKIMConfig enables signing when it has keys
+type DKIMConfig struct {
+	Domain string
+	Keys   []DKIMKey
+}
+
+type DKIMKey struct {
+	Selector   string
+	KeyFile    string    `mapstructure:"key_file"`   // PEM encoded RSA or Ed25519 private key
+	ValidFrom  time.Time `mapstructure:"valid_from"` // zero means always
+	ValidUntil time.Time `mapstructure:"valid_until"`
+}
+
+type WebhookConfig struct {
+	Timeout time.Duration
+
+	RetryMaxAttempts  int
+	RetryInitialDelay time.Duration
+
+	Workers     int // consumers of the delivery queue
+	MaxFailures int // consecutive failed attempts after which a webhook is disabled
+}
+
+type SMSConfig struct {
+	Provider          string // http or fake
+	AppName           string
+	HTTP              SMSHTTPConfig
+	StatusCallbackURL string // this service's delivery status endpoint given to the gateway
+
+	RetryMaxAttempts  int
+	RetryInitialDelay time.Duration
+	Workers           int
+
+	NumberLimit MailLimit // messages to one number, shared by all replicas through Redis
+}
+
+type SMSHTTPConfig struct {
+	URL     string
+	Token   string
+	From    string
+	Timeout time.Duration
+}
+
+type RabbitMQConfig struct {
+	ReconnectInitialDelay time.Duration // doubled after every failed attempt
+	ReconnectMaxDelay     time.Duration
+	PublishTimeout        time.Duration // how long publishing waits for the connection to come back
+}
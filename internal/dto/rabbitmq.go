@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,11 +12,42 @@ type MQNotificateUserCode struct {
 	Code  int    `json:"code"`
 }
 
+type MQSignInCodeSMS struct {
+	Phone string `json:"phone"` // E.164
+	Code  int    `json:"code"`
+}
+
+type MQPasswordReset struct {
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	ResetURL  string    `json:"reset_url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type MQEmailChange struct {
+	OldEmail   string    `json:"old_email"`
+	NewEmail   string    `json:"new_email"`
+	Username   string    `json:"username"`
+	ConfirmURL string    `json:"confirm_url"` // sent to the new address only
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type MQNewDeviceSignIn struct {
+	Email      string    `json:"email"`
+	Username   string    `json:"username"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	SignedInAt time.Time `json:"signed_in_at"`
+}
+
 type MQUserCreated struct {
 	ID          uuid.UUID `json:"id"`
 	Username    string    `json:"username"`
 	DisplayName *string   `json:"display_name"`
 	AvatarURL   *string   `json:"avatar_url"`
+	Email       *string   `json:"email"`
+	Locale      *string   `json:"locale"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 type MQPostCreated struct {
@@ -41,3 +73,68 @@ type MQPostValidationStatusUpdate struct {
 	UserID    uuid.UUID  `json:"user_id"`
 	StatusMsg string     `json:"status_msg"`
 }
+
+type MQDigest struct {
+	UserID        uuid.UUID      `json:"user_id"`
+	Email         string         `json:"email"`
+	Username      string         `json:"username"`
+	Frequency     string         `json:"frequency"`
+	Notifications []MQDigestItem `json:"notifications"`
+	TotalUnread   int64          `json:"total_unread"`
+}
+
+type MQDigestItem struct {
+	Type       string    `json:"type"`
+	Content    string    `json:"content"`
+	ResourceID string    `json:"resource_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// MQMailEvent is a bounce or complaint published by the mail relay
+type MQMailEvent struct {
+	Type       string    `json:"type"`        // bounce or complaint
+	BounceType string    `json:"bounce_type"` // hard or soft, bounces only
+	Email      string    `json:"email"`
+	Detail     string    `json:"detail"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// MQMailRateLimited tells the producer that a mail was dropped, e.g. so the auth service can tell the user to wait
+type MQMailRateLimited struct {
+	Type       string    `json:"type"`
+	Email      string    `json:"email"`
+	Scope      string    `json:"scope"` // recipient or type
+	RetryAfter time.Time `json:"retry_after"`
+}
+
+// MQSMSRateLimited tells the producer that an sms was dropped, like MQMailRateLimited
+type MQSMSRateLimited struct {
+	Type       string    `json:"type"`
+	Phone      string    `json:"phone"`
+	RetryAfter time.Time `json:"retry_after"`
+}
+
+// MQWebhookDelivery is one event waiting to be delivered to one webhook
+type MQWebhookDelivery struct {
+	EventID   uuid.UUID       `json:"event_id"`
+	WebhookID int64           `json:"webhook_id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"` // the body posted to the webhook
+}
+
+// MQPushDelivery is a notification routed to the user's browsers and devices
+type MQPushDelivery struct {
+	ReceiverID uuid.UUID `json:"receiver_id"`
+	Type       string    `json:"type"`
+	Content    string    `json:"content"`
+	ResourceID string    `json:"resource_id"`
+	Priority   string    `json:"priority"`
+}
+
+// MQPresenceChanged is published when a user's presence changes
+type MQPresenceChanged struct {
+	UserID        uuid.UUID `json:"user_id"`
+	State         string    `json:"state"`
+	PreviousState string    `json:"previous_state"`
+	ChangedAt     time.Time `json:"changed_at"`
+}
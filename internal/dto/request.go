@@ -1,7 +1,81 @@
 package dto
 
+import (
+	"encoding/json"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/google/uuid"
+)
+
 type CreateNotificationManually struct {
-	Title        string `json:"title" binding:"required,max=255"`
-	Content      string `json:"content" binding:"required"`
-	ResourceLink string `json:"resource_link" binding:"max=255"`
+	Title        string                            `json:"title" binding:"required,max=255"`
+	Content      string                            `json:"content" binding:"required"`
+	ResourceLink string                            `json:"resource_link" binding:"max=255"`
+	Variant      string                            `json:"variant"`
+	Severity     string                            `json:"severity"`
+	Dismissible  *bool                             `json:"dismissible"`
+	Audience     *model.GlobalNotificationAudience `json:"audience"`
+}
+
+type GlobalNotificationAudienceDryRun struct {
+	Audience *model.GlobalNotificationAudience `json:"audience"`
+}
+
+type UpdateGlobalNotification struct {
+	Title        *string `json:"title"`
+	Content      *string `json:"content"`
+	ResourceLink *string `json:"resource_link"`
+	Variant      *string `json:"variant"`
+	Severity     *string `json:"severity"`
+	Dismissible  *bool   `json:"dismissible"`
+}
+
+type GlobalNotificationTransition struct {
+	Comment string `json:"comment"`
+}
+
+type UpdateNotificationPreferences struct {
+	DigestFrequency *string `json:"digest_frequency"`
+	Timezone        *string `json:"timezone"`
+	PushEnabled     *bool   `json:"push_enabled"`
+	// both "15:04" to set quiet hours, both empty to remove them
+	QuietHoursStart *string `json:"quiet_hours_start"`
+	QuietHoursEnd   *string `json:"quiet_hours_end"`
+}
+
+type EmailPreview struct {
+	Data json.RawMessage `json:"data"` // the queue message, the email type's sample when empty
+}
+
+type EmailTestSend struct {
+	To   string          `json:"to"`
+	Data json.RawMessage `json:"data"`
+}
+
+type RegisterDevice struct {
+	Platform string `json:"platform"`
+	Token    string `json:"token"`
+}
+
+type CreateWebhook struct {
+	URL   string   `json:"url"`
+	Types []string `json:"types"`
+}
+
+// UpdateWebhook leaves nil fields unchanged
+type UpdateWebhook struct {
+	URL     *string  `json:"url"`
+	Types   []string `json:"types"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// SMSStatusCallback is a delivery status posted by the SMS gateway
+type SMSStatusCallback struct {
+	ID     string `json:"id"` // the message ID the gateway returned when it accepted the message
+	Status string `json:"status"`
+	Error  string `json:"error"`
+}
+
+type PresenceBatch struct {
+	UserIDs []uuid.UUID `json:"user_ids"`
 }
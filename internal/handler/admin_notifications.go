@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) notificationsListGlobal(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	limit, err0 := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, err1 := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err0 != nil || err1 != nil {
+		h.Respond(w, Resp{"error": errInvalidLimitOffset.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	notifications, err := h.services.Notification.ListGlobalNotifications(r.Context(), r.URL.Query().Get("status"), limit, offset)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, notifications, http.StatusOK)
+}
+
+func (h *Handler) notificationsUpdateGlobal(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	notificationID, err := strconv.ParseInt(r.PathValue("nId"), 10, 64)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	var input dto.UpdateGlobalNotification
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	notification, err := h.services.Notification.UpdateGlobalNotification(r.Context(), notificationID, admin.ID, input)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, notification, http.StatusOK)
+}
+
+func (h *Handler) notificationsDeleteGlobal(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	notificationID, err := strconv.ParseInt(r.PathValue("nId"), 10, 64)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.services.Notification.DeleteGlobalNotification(r.Context(), notificationID, admin.ID); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{}, http.StatusOK)
+}
+
+func (h *Handler) notificationsGetGlobalStats(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	notificationID, err := strconv.ParseInt(r.PathValue("nId"), 10, 64)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.services.Notification.GetGlobalNotificationStats(r.Context(), notificationID, r.URL.Query().Get("interval"))
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, stats, http.StatusOK)
+}
+
+func (h *Handler) notificationsTransitionGlobal(admin *model.User, action string, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	notificationID, err := strconv.ParseInt(r.PathValue("nId"), 10, 64)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	// the comment is optional, so an empty body is fine
+	var input dto.GlobalNotificationTransition
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	transitions := map[string]func(ctx context.Context, id int64, adminID uuid.UUID, comment string) error{
+		"submit": h.services.Notification.SubmitGlobalNotification,
+		"approve": h.services.Notification.ApproveGlobalNotification,
+		"reject": h.services.Notification.RejectGlobalNotification,
+		"publish": h.services.Notification.PublishGlobalNotification,
+	}
+	transition, ok := transitions[action]
+	if !ok {
+		return
+	}
+
+	if err := transition(r.Context(), notificationID, admin.ID, input.Comment); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{}, http.StatusOK)
+}
+
+func (h *Handler) notificationsGetGlobalAuditTrail(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	notificationID, err := strconv.ParseInt(r.PathValue("nId"), 10, 64)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.services.Notification.GetGlobalNotificationAuditTrail(r.Context(), notificationID)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, events, http.StatusOK)
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+)
+
+func (h *Handler) deadLetterQueuesGet(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	queues, err := h.services.DeadLetter.GetDeadLetterQueues(r.Context())
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, queues, http.StatusOK)
+}
+
+func (h *Handler) deadLettersGet(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		h.Respond(w, Resp{"error": errInvalidLimit.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	deadLetters, err := h.services.DeadLetter.GetDeadLetters(r.Context(), r.PathValue("queue"), limit)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, deadLetters, http.StatusOK)
+}
+
+func (h *Handler) deadLetterGet(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	deadLetter, err := h.services.DeadLetter.GetDeadLetter(r.Context(), r.PathValue("queue"), r.PathValue("id"))
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, deadLetter, http.StatusOK)
+}
+
+func (h *Handler) deadLettersReplay(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	replayed, err := h.services.DeadLetter.ReplayDeadLetters(r.Context(), r.PathValue("queue"), admin.ID)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error(), "replayed": replayed}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{"replayed": replayed}, http.StatusOK)
+}
+
+func (h *Handler) deadLetterReplay(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	if err := h.services.DeadLetter.ReplayDeadLetter(r.Context(), r.PathValue("queue"), r.PathValue("id"), admin.ID); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{"replayed": 1}, http.StatusOK)
+}
+
+func (h *Handler) deadLettersPurge(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	purged, err := h.services.DeadLetter.PurgeDeadLetters(r.Context(), r.PathValue("queue"), admin.ID)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{"purged": purged}, http.StatusOK)
+}
+
+func (h *Handler) deadLetterPurge(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	if err := h.services.DeadLetter.PurgeDeadLetter(r.Context(), r.PathValue("queue"), r.PathValue("id"), admin.ID); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{"purged": 1}, http.StatusOK)
+}
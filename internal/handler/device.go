@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+)
+
+func (h *Handler) pushGetDevices(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	devices, err := h.services.Device.GetDevices(r.Context(), user.ID)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, devices, http.StatusOK)
+}
+
+func (h *Handler) pushRegisterDevice(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	var input dto.RegisterDevice
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.services.Device.RegisterDevice(r.Context(), user.ID, input.Platform, input.Token)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{"id": id}, http.StatusCreated)
+}
+
+func (h *Handler) pushRemoveDevice(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	deviceID, err := strconv.ParseInt(r.PathValue("dId"), 10, 64)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.services.Device.RemoveDevice(r.Context(), user.ID, deviceID); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{}, http.StatusOK)
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+)
+
+func (h *Handler) emailAuditSearch(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	limit, err0 := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, err1 := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err0 != nil || err1 != nil {
+		h.Respond(w, Resp{"error": errInvalidLimitOffset.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	from, err0 := parseOptionalTime(r.URL.Query().Get("from"))
+	to, err1 := parseOptionalTime(r.URL.Query().Get("to"))
+	if err0 != nil || err1 != nil {
+		h.Respond(w, Resp{"error": errInvalidTime.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	records, err := h.services.EmailAudit.Search(r.Context(), r.URL.Query().Get("recipient"), from, to, limit, offset)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, records, http.StatusOK)
+}
+
+func parseOptionalTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
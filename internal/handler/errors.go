@@ -1,6 +1,11 @@
 package handler
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+
+	"github.com/BloggingApp/notification-service/internal/service"
+)
 
 var (
 	errNoToken              = errors.New("there is no token")
@@ -8,4 +13,80 @@ var (
 	errInvalidUserID        = errors.New("invalid user ID")
 	errNotAdmin             = errors.New("you are not an admin")
 	errInvalidLimitOffset = errors.New("limit and offset must be integer")
+	errInvalidLimit = errors.New("limit must be integer")
+	errInvalidTime = errors.New("from and to must be RFC 3339 times")
+	errInvalidInternalToken = errors.New("invalid internal api token")
 )
+
+// errorStatusCodes maps service errors caused by the client to response status codes.
+// Any other error is an internal one.
+var errorStatusCodes = map[int][]error{
+	http.StatusBadRequest: {
+		service.ErrInvalidInputForGlobalNotification,
+		service.ErrInvalidAudience,
+		service.ErrInvalidStatsInterval,
+		service.ErrGlobalNotificationContentTooLong,
+		service.ErrInvalidGlobalNotificationVariant,
+		service.ErrInvalidGlobalNotificationSeverity,
+		service.ErrInvalidDigestFrequency,
+		service.ErrInvalidTimezone,
+		service.ErrInvalidQuietHours,
+		service.ErrInvalidUnsubscribeToken,
+		service.ErrInvalidTimeRange,
+		service.ErrInvalidEmailData,
+		service.ErrInvalidEmail,
+		service.ErrInvalidPushSubscription,
+		service.ErrTooManyPushSubscriptions,
+		service.ErrUnsupportedPlatform,
+		service.ErrInvalidDeviceToken,
+		service.ErrTooManyDevices,
+		service.ErrInvalidWebhookURL,
+		service.ErrInvalidWebhookTypes,
+		service.ErrTooManyWebhooks,
+		service.ErrInvalidSMSStatus,
+		service.ErrInvalidPresenceBatch,
+		service.ErrInvalidDeadLetterID,
+	},
+	http.StatusUnauthorized: {
+		service.ErrInvalidSMSCallbackToken,
+	},
+	http.StatusForbidden: {
+		service.ErrSelfApproval,
+	},
+	http.StatusNotFound: {
+		service.ErrGlobalNotificationNotFound,
+		service.ErrNotificationNotFound,
+		service.ErrSuppressionNotFound,
+		service.ErrEmailTypeNotFound,
+		service.ErrPushSubscriptionNotFound,
+		service.ErrDeviceNotFound,
+		service.ErrWebhookNotFound,
+		service.ErrSMSMessageNotFound,
+		service.ErrQueueNotFound,
+		service.ErrDeadLetterNotFound,
+	},
+	http.StatusConflict: {
+		service.ErrGlobalNotificationNotDismissible,
+		service.ErrGlobalNotificationStatusChanged,
+		service.ErrInvalidGlobalNotificationTransition,
+		service.ErrEmailSuppressed,
+	},
+	http.StatusServiceUnavailable: {
+		service.ErrPushDisabled,
+	},
+	http.StatusGone: {
+		service.ErrUnsubscribeTokenExpired,
+	},
+}
+
+func statusCodeFromError(err error) int {
+	for statusCode, errs := range errorStatusCodes {
+		for _, e := range errs {
+			if errors.Is(err, e) {
+				return statusCode
+			}
+		}
+	}
+
+	return http.StatusInternalServerError
+}
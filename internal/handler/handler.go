@@ -63,6 +63,158 @@ func (h *Handler) SetupRoutes() http.Handler {
 		h.notificationsGet(user, w, r)
 	})
 
+	mux.HandleFunc("/api/v1/notifications/{nId}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			return
+		}
+
+		user, err := h.authMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		h.notificationsMarkAsRead(user, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/notifications/preferences", func(w http.ResponseWriter, r *http.Request) {
+		user, err := h.authMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			h.preferencesGet(user, w, r)
+		} else if r.Method == http.MethodPut {
+			h.preferencesUpdate(user, w, r)
+		}
+	})
+
+	// public, the token in the query authorizes the request. POST is also what mail clients
+	// send for one-click unsubscribe (RFC 8058).
+	mux.HandleFunc("/api/v1/unsubscribe", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			h.unsubscribeCheck(w, r)
+		} else if r.Method == http.MethodPost {
+			h.unsubscribe(w, r)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/sms/status", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			return
+		}
+
+		h.smsStatusCallback(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/push/vapid-public-key", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			return
+		}
+
+		h.pushVAPIDPublicKey(w, r)
+	})
+
+	mux.HandleFunc("/api/v1/push/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		user, err := h.authMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			h.pushGetSubscriptions(user, w, r)
+		} else if r.Method == http.MethodPost {
+			h.pushRegisterSubscription(user, w, r)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/push/subscriptions/{sId}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			return
+		}
+
+		user, err := h.authMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		h.pushRemoveSubscription(user, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/push/devices", func(w http.ResponseWriter, r *http.Request) {
+		user, err := h.authMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			h.pushGetDevices(user, w, r)
+		} else if r.Method == http.MethodPost {
+			h.pushRegisterDevice(user, w, r)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/push/devices/{dId}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			return
+		}
+
+		user, err := h.authMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		h.pushRemoveDevice(user, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/webhooks", func(w http.ResponseWriter, r *http.Request) {
+		user, err := h.authMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			h.webhooksGet(user, w, r)
+		} else if r.Method == http.MethodPost {
+			h.webhooksCreate(user, w, r)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/webhooks/{wId}", func(w http.ResponseWriter, r *http.Request) {
+		user, err := h.authMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		if r.Method == http.MethodPut {
+			h.webhooksUpdate(user, w, r)
+		} else if r.Method == http.MethodDelete {
+			h.webhooksDelete(user, w, r)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/webhooks/{wId}/deliveries", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			return
+		}
+
+		user, err := h.authMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		h.webhooksGetDeliveries(user, w, r)
+	})
+
 	mux.HandleFunc("/api/v1/notifications/global", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodPost {
 			admin, err := h.adminMiddleware(r)
@@ -97,6 +249,287 @@ func (h *Handler) SetupRoutes() http.Handler {
 		h.notificationsMarkGlobalNotificationAsRead(user, w, r)
 	})
 
+	mux.HandleFunc("/api/v1/admin/notifications/global", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.notificationsListGlobal(admin, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/admin/notifications/global/{nId}", func(w http.ResponseWriter, r *http.Request) {
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		if r.Method == http.MethodPut {
+			h.notificationsUpdateGlobal(admin, w, r)
+		} else if r.Method == http.MethodDelete {
+			h.notificationsDeleteGlobal(admin, w, r)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/admin/notifications/global/{nId}/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.notificationsGetGlobalStats(admin, w, r)
+	})
+
+	for _, action := range []string{"submit", "approve", "reject", "publish"} {
+		mux.HandleFunc("/api/v1/admin/notifications/global/{nId}/"+action, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				return
+			}
+
+			admin, err := h.adminMiddleware(r)
+			if err != nil {
+				h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+				return
+			}
+
+			h.notificationsTransitionGlobal(admin, action, w, r)
+		})
+	}
+
+	mux.HandleFunc("/api/v1/admin/notifications/global/{nId}/audit", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.notificationsGetGlobalAuditTrail(admin, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/admin/notifications/global/dry-run", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.notificationsAudienceDryRun(admin, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/admin/mail/suppressions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.suppressionsList(admin, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/admin/mail/suppressions/{email}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.suppressionsRemove(admin, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/admin/routing/decisions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.routingDecisionsGet(admin, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/admin/dead-letters", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.deadLetterQueuesGet(admin, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/admin/dead-letters/{queue}", func(w http.ResponseWriter, r *http.Request) {
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			h.deadLettersGet(admin, w, r)
+		} else if r.Method == http.MethodDelete {
+			h.deadLettersPurge(admin, w, r)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/admin/dead-letters/{queue}/replay", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.deadLettersReplay(admin, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/admin/dead-letters/{queue}/{id}", func(w http.ResponseWriter, r *http.Request) {
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			h.deadLetterGet(admin, w, r)
+		} else if r.Method == http.MethodDelete {
+			h.deadLetterPurge(admin, w, r)
+		}
+	})
+
+	mux.HandleFunc("/api/v1/admin/dead-letters/{queue}/{id}/replay", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.deadLetterReplay(admin, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/admin/mail/audit", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.emailAuditSearch(admin, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/admin/mail/types", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.mailListTypes(admin, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/admin/mail/types/{type}/preview", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.mailPreview(admin, w, r)
+	})
+
+	mux.HandleFunc("/api/v1/admin/mail/types/{type}/test-send", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			return
+		}
+
+		admin, err := h.adminMiddleware(r)
+		if err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusForbidden)
+			return
+		}
+
+		h.mailTestSend(admin, w, r)
+	})
+
+	// internal, called by other services rather than users
+	mux.HandleFunc("/internal/v1/presence", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			return
+		}
+
+		if err := h.internalMiddleware(r); err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		h.presenceGetBatch(w, r)
+	})
+
+	mux.HandleFunc("/internal/v1/presence/{userID}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			return
+		}
+
+		if err := h.internalMiddleware(r); err != nil {
+			h.Respond(w, Resp{"error": err.Error()}, http.StatusUnauthorized)
+			return
+		}
+
+		h.presenceGet(w, r)
+	})
+
 	return mux
 }
 
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// internalMiddleware authorizes calls from other services with the shared INTERNAL_API_TOKEN.
+// Without the token configured every call is rejected.
+func (h *Handler) internalMiddleware(r *http.Request) error {
+	expected := os.Getenv("INTERNAL_API_TOKEN")
+	if expected == "" {
+		return errInvalidInternalToken
+	}
+
+	bearerHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(bearerHeader, "Bearer ") {
+		return errNoToken
+	}
+
+	token := strings.TrimPrefix(bearerHeader, "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+		return errInvalidInternalToken
+	}
+
+	return nil
+}
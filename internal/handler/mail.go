@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+)
+
+func (h *Handler) mailListTypes(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	h.Respond(w, h.services.Mail.ListEmailTypes(), http.StatusOK)
+}
+
+func (h *Handler) mailPreview(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	// the body is optional, the sample is rendered without it
+	var input dto.EmailPreview
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	previews, err := h.services.Mail.PreviewEmail(r.Context(), r.PathValue("type"), input.Data)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, previews, http.StatusOK)
+}
+
+func (h *Handler) mailTestSend(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	var input dto.EmailTestSend
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	messageIDs, err := h.services.Mail.SendTestEmail(r.Context(), admin.ID, r.PathValue("type"), input.To, input.Data)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{"message_ids": messageIDs}, http.StatusOK)
+}
@@ -35,6 +35,25 @@ func (h *Handler) notificationsGet(user *model.User, w http.ResponseWriter, r *h
 	h.Respond(w, notifications, http.StatusOK)
 }
 
+func (h *Handler) notificationsMarkAsRead(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	notificationID, err := strconv.ParseInt(r.PathValue("nId"), 10, 64)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.services.Notification.MarkAsRead(r.Context(), user.ID, notificationID); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{}, http.StatusOK)
+}
+
 func (h *Handler) notificationsCreateManually(admin *model.User, w http.ResponseWriter, r *http.Request) {
 	if admin == nil {
 		return
@@ -46,17 +65,47 @@ func (h *Handler) notificationsCreateManually(admin *model.User, w http.Response
 		return
 	}
 
-	if err := h.services.Notification.CreateGlobalNotification(r.Context(), model.GlobalNotification{
+	dismissible := true
+	if input.Dismissible != nil {
+		dismissible = *input.Dismissible
+	}
+
+	id, err := h.services.Notification.CreateGlobalNotification(r.Context(), model.GlobalNotification{
 		PosterID: admin.ID,
 		Title: input.Title,
 		Content: input.Content,
 		ResourceLink: input.ResourceLink,
-	}); err != nil {
-		h.Respond(w, Resp{"error": err.Error()}, http.StatusInternalServerError)
+		Variant: input.Variant,
+		Severity: input.Severity,
+		Dismissible: dismissible,
+		Audience: input.Audience,
+	})
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
 		return
 	}
 
-	h.Respond(w, Resp{}, http.StatusCreated)
+	h.Respond(w, Resp{"id": id}, http.StatusCreated)
+}
+
+func (h *Handler) notificationsAudienceDryRun(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	var input dto.GlobalNotificationAudienceDryRun
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	count, err := h.services.Notification.CountGlobalNotificationAudience(r.Context(), input.Audience)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{"audience_size": count}, http.StatusOK)
 }
 
 func (h *Handler) notificationsGetGlobal(user *model.User, w http.ResponseWriter, r *http.Request) {
@@ -98,7 +147,7 @@ func (h *Handler) notificationsMarkGlobalNotificationAsRead(user *model.User, w
 	}
 
 	if err := h.services.Notification.MarkGlobalNotificationAsRead(r.Context(), user.ID, int64(notificationID)); err != nil {
-		h.Respond(w, Resp{"error": err.Error()}, http.StatusInternalServerError)
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
 		return
 	}
 
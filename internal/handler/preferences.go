@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+)
+
+func (h *Handler) preferencesGet(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	prefs, err := h.services.Preferences.Get(r.Context(), user.ID)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, prefs, http.StatusOK)
+}
+
+func (h *Handler) preferencesUpdate(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	var input dto.UpdateNotificationPreferences
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	prefs, err := h.services.Preferences.Update(r.Context(), user.ID, input)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, prefs, http.StatusOK)
+}
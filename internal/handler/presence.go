@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) presenceGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		h.Respond(w, Resp{"error": errInvalidUserID.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	presence, err := h.services.Presence.GetPresence(r.Context(), userID)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, presence, http.StatusOK)
+}
+
+func (h *Handler) presenceGetBatch(w http.ResponseWriter, r *http.Request) {
+	var input dto.PresenceBatch
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	presences, err := h.services.Presence.GetPresences(r.Context(), input.UserIDs)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, presences, http.StatusOK)
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/webpush"
+)
+
+func (h *Handler) pushVAPIDPublicKey(w http.ResponseWriter, r *http.Request) {
+	key, err := h.services.Push.VAPIDPublicKey()
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{"public_key": key}, http.StatusOK)
+}
+
+func (h *Handler) pushGetSubscriptions(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	subs, err := h.services.Push.GetSubscriptions(r.Context(), user.ID)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, subs, http.StatusOK)
+}
+
+func (h *Handler) pushRegisterSubscription(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	var input webpush.Subscription
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.services.Push.RegisterSubscription(r.Context(), user.ID, input, r.UserAgent())
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{"id": id}, http.StatusCreated)
+}
+
+func (h *Handler) pushRemoveSubscription(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	subscriptionID, err := strconv.ParseInt(r.PathValue("sId"), 10, 64)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.services.Push.RemoveSubscription(r.Context(), user.ID, subscriptionID); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{}, http.StatusOK)
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/google/uuid"
+)
+
+func (h *Handler) routingDecisionsGet(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	userID, err := uuid.Parse(r.URL.Query().Get("user_id"))
+	if err != nil {
+		h.Respond(w, Resp{"error": errInvalidUserID.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	limit, err0 := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, err1 := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err0 != nil || err1 != nil {
+		h.Respond(w, Resp{"error": errInvalidLimitOffset.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	decisions, err := h.services.Notification.GetRoutingDecisions(r.Context(), userID, limit, offset)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, decisions, http.StatusOK)
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+)
+
+// smsStatusCallback is called by the SMS gateway, the token in the query authorizes it
+func (h *Handler) smsStatusCallback(w http.ResponseWriter, r *http.Request) {
+	var input dto.SMSStatusCallback
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.services.SMS.UpdateDeliveryStatus(r.Context(), r.URL.Query().Get("token"), input); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{}, http.StatusOK)
+}
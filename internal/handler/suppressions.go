@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+)
+
+func (h *Handler) suppressionsList(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	limit, err0 := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, err1 := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err0 != nil || err1 != nil {
+		h.Respond(w, Resp{"error": errInvalidLimitOffset.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	suppressions, err := h.services.Suppression.List(r.Context(), r.URL.Query().Get("email"), limit, offset)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, suppressions, http.StatusOK)
+}
+
+func (h *Handler) suppressionsRemove(admin *model.User, w http.ResponseWriter, r *http.Request) {
+	if admin == nil {
+		return
+	}
+
+	if err := h.services.Suppression.Remove(r.Context(), r.PathValue("email"), admin.ID); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{}, http.StatusOK)
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+)
+
+func (h *Handler) unsubscribeCheck(w http.ResponseWriter, r *http.Request) {
+	claims, err := h.services.Preferences.CheckUnsubscribeToken(r.URL.Query().Get("token"))
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{"category": claims.Category}, http.StatusOK)
+}
+
+func (h *Handler) unsubscribe(w http.ResponseWriter, r *http.Request) {
+	claims, err := h.services.Preferences.Unsubscribe(r.Context(), r.URL.Query().Get("token"))
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{"category": claims.Category, "unsubscribed": true}, http.StatusOK)
+}
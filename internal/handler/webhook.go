@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+)
+
+func (h *Handler) webhooksGet(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	webhooks, err := h.services.Webhook.GetWebhooks(r.Context(), user.ID)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, webhooks, http.StatusOK)
+}
+
+func (h *Handler) webhooksCreate(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	var input dto.CreateWebhook
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := h.services.Webhook.CreateWebhook(r.Context(), user.ID, input)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, webhook, http.StatusCreated)
+}
+
+func (h *Handler) webhooksUpdate(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	webhookID, err := strconv.ParseInt(r.PathValue("wId"), 10, 64)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	var input dto.UpdateWebhook
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := h.services.Webhook.UpdateWebhook(r.Context(), user.ID, webhookID, input)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, webhook, http.StatusOK)
+}
+
+func (h *Handler) webhooksDelete(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	webhookID, err := strconv.ParseInt(r.PathValue("wId"), 10, 64)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.services.Webhook.DeleteWebhook(r.Context(), user.ID, webhookID); err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, Resp{}, http.StatusOK)
+}
+
+func (h *Handler) webhooksGetDeliveries(user *model.User, w http.ResponseWriter, r *http.Request) {
+	if user == nil {
+		return
+	}
+
+	webhookID, err := strconv.ParseInt(r.PathValue("wId"), 10, 64)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	limit, err0 := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, err1 := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err0 != nil || err1 != nil {
+		h.Respond(w, Resp{"error": errInvalidLimitOffset.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := h.services.Webhook.GetWebhookDeliveries(r.Context(), user.ID, webhookID, limit, offset)
+	if err != nil {
+		h.Respond(w, Resp{"error": err.Error()}, statusCodeFromError(err))
+		return
+	}
+
+	h.Respond(w, deliveries, http.StatusOK)
+}
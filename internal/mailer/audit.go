@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"net/textproto"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+)
+
+// audit records the outcome of a send attempt. SMTP replies are recorded as the response,
+// any other error as the detail.
+func (m *Mailer) audit(typeName string, recipient string, messageID string, attempt int, sendErr error) {
+	record := model.EmailAuditRecord{
+		Type: typeName,
+		Recipient: recipient,
+		MessageID: messageID,
+		Status: model.EMAIL_STATUS_SENT,
+		Attempt: attempt,
+	}
+
+	if sendErr != nil {
+		switch {
+		case errors.Is(sendErr, ErrRecipientSuppressed):
+			record.Status = model.EMAIL_STATUS_SUPPRESSED
+		case IsPermanent(sendErr):
+			record.Status = model.EMAIL_STATUS_REJECTED
+		default:
+			record.Status = model.EMAIL_STATUS_FAILED
+		}
+
+		var tpErr *textproto.Error
+		if errors.As(sendErr, &tpErr) {
+			record.SMTPResponse = tpErr.Error()
+		} else {
+			record.Detail = sendErr.Error()
+		}
+	}
+
+	if err := m.repo.Postgres.EmailAudit.Create(context.Background(), record); err != nil {
+		m.logger.Sugar().Errorf("Failed to record %s mail to(%s): %s", typeName, recipient, err.Error())
+	}
+}
@@ -0,0 +1,149 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/config"
+	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+	"github.com/emersion/go-msgauth/dkim"
+)
+
+// dkimHeaders are the signed headers. Absent ones are signed too, so they can't be added on the way.
+var dkimHeaders = []string{
+	"From",
+	"To",
+	"Subject",
+	"Date",
+	"Message-ID",
+	"MIME-Version",
+	"Content-Type",
+	"List-Unsubscribe",
+	"List-Unsubscribe-Post",
+}
+
+type dkimKey struct {
+	selector   string
+	signer     crypto.Signer
+	validFrom  time.Time
+	validUntil time.Time
+}
+
+func (k *dkimKey) activeAt(t time.Time) bool {
+	return !t.Before(k.validFrom) && (k.validUntil.IsZero() || t.Before(k.validUntil))
+}
+
+// DKIMTransport signs every message with the DKIM keys active at the time of sending
+// and passes it on. Keys are rotated by publishing the next selector, adding its key
+// with valid_from in the future and giving the old key a valid_until. Overlapping keys,
+// e.g. an RSA and an Ed25519 one, all add their signatures.
+type DKIMTransport struct {
+	next   Transport
+	domain string
+	keys   []*dkimKey
+}
+
+func NewDKIMTransport(next Transport, cfg config.DKIMConfig) (*DKIMTransport, error) {
+	if cfg.Domain == "" {
+		return nil, errors.New("dkim domain is required")
+	}
+
+	t := &DKIMTransport{
+		next: next,
+		domain: cfg.Domain,
+	}
+	for _, keyCfg := range cfg.Keys {
+		pemBytes, err := os.ReadFile(keyCfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dkim key(%s): %w", keyCfg.Selector, err)
+		}
+
+		signer, err := parseDKIMKey(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse dkim key(%s): %w", keyCfg.Selector, err)
+		}
+
+		t.keys = append(t.keys, &dkimKey{
+			selector: keyCfg.Selector,
+			signer: signer,
+			validFrom: keyCfg.ValidFrom,
+			validUntil: keyCfg.ValidUntil,
+		})
+	}
+
+	return t, nil
+}
+
+// parseDKIMKey reads a PEM encoded PKCS#8 RSA or Ed25519 key, or a PKCS#1 RSA key
+func parseDKIMKey(pemBytes []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, must be RSA or Ed25519", key)
+	}
+}
+
+func (t *DKIMTransport) Send(from string, to []string, msg []byte) error {
+	signed, err := t.sign(msg)
+	if err != nil {
+		// a broken key won't fix itself
+		return rabbitmq.Permanent(fmt.Errorf("failed to dkim sign: %w", err))
+	}
+
+	return t.next.Send(from, to, signed)
+}
+
+func (t *DKIMTransport) sign(msg []byte) ([]byte, error) {
+	now := time.Now()
+	signatures := 0
+	for _, key := range t.keys {
+		if !key.activeAt(now) {
+			continue
+		}
+
+		var signed bytes.Buffer
+		if err := dkim.Sign(&signed, bytes.NewReader(msg), &dkim.SignOptions{
+			Domain: t.domain,
+			Selector: key.selector,
+			Signer: key.signer,
+			HeaderCanonicalization: dkim.CanonicalizationRelaxed,
+			BodyCanonicalization: dkim.CanonicalizationRelaxed,
+			HeaderKeys: dkimHeaders,
+		}); err != nil {
+			return nil, err
+		}
+		msg = signed.Bytes()
+		signatures++
+	}
+	// sending unsigned would fail DMARC without anyone noticing
+	if signatures == 0 {
+		return nil, ErrNoActiveDKIMKey
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,270 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/config"
+	"github.com/emersion/go-msgauth/dkim"
+)
+
+const testDKIMDomain = "example.com"
+
+type testDKIMKey struct {
+	selector string
+	signer   crypto.Signer
+}
+
+func generateRSAKey(t *testing.T, selector string) testDKIMKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testDKIMKey{selector: selector, signer: key}
+}
+
+func generateEd25519Key(t *testing.T, selector string) testDKIMKey {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testDKIMKey{selector: selector, signer: key}
+}
+
+// writeKeyFile writes the key as PKCS#8 PEM, the format the config points to
+func writeKeyFile(t *testing.T, key testDKIMKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key.signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), key.selector+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// dkimRecord builds the DNS TXT record publishing the key's public half
+func dkimRecord(t *testing.T, key testDKIMKey) string {
+	t.Helper()
+
+	switch pub := key.signer.Public().(type) {
+	case *rsa.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "v=DKIM1; k=rsa; p=" + base64.StdEncoding.EncodeToString(der)
+	case ed25519.PublicKey:
+		return "v=DKIM1; k=ed25519; p=" + base64.StdEncoding.EncodeToString(pub)
+	}
+
+	t.Fatalf("unsupported key type %T", key.signer)
+	return ""
+}
+
+// lookupTXT stands in for DNS with the records of the published keys
+func lookupTXT(t *testing.T, keys ...testDKIMKey) func(domain string) ([]string, error) {
+	records := map[string]string{}
+	for _, key := range keys {
+		records[key.selector+"._domainkey."+testDKIMDomain] = dkimRecord(t, key)
+	}
+
+	return func(domain string) ([]string, error) {
+		record, ok := records[domain]
+		if !ok {
+			return nil, errors.New("no such record: " + domain)
+		}
+		return []string{record}, nil
+	}
+}
+
+func testMessage(t *testing.T) []byte {
+	t.Helper()
+
+	msg := NewMessage(mail.Address{Name: "BloggingApp", Address: "noreply@" + testDKIMDomain}, []mail.Address{{Address: "jane@example.org"}}, "Verify your email")
+	msg.Text = "Your code is 123456"
+	msg.HTML = "<p>Your code is <b>123456</b></p>"
+	msg.Headers["List-Unsubscribe"] = "<https://example.com/unsubscribe?token=x>"
+
+	raw, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// signWith sends a message through a DKIMTransport with the keys and returns what reached the next transport
+func signWith(t *testing.T, keys []config.DKIMKey) ([]byte, error) {
+	t.Helper()
+
+	memory := NewMemoryTransport()
+	transport, err := NewDKIMTransport(memory, config.DKIMConfig{Domain: testDKIMDomain, Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := transport.Send("noreply@"+testDKIMDomain, []string{"jane@example.org"}, testMessage(t)); err != nil {
+		return nil, err
+	}
+
+	messages := memory.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected 1 sent message, got %d", len(messages))
+	}
+	return messages[0].Msg, nil
+}
+
+// verifiedSelectors verifies every signature of the message and returns the selectors that signed it
+func verifiedSelectors(t *testing.T, signed []byte, published ...testDKIMKey) []string {
+	t.Helper()
+
+	verifications, err := dkim.VerifyWithOptions(bytes.NewReader(signed), &dkim.VerifyOptions{LookupTXT: lookupTXT(t, published...)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range verifications {
+		if v.Err != nil {
+			t.Fatalf("signature of %s didn't verify: %s", v.Domain, v.Err)
+		}
+		if v.Domain != testDKIMDomain {
+			t.Fatalf("expected signing domain %s, got %s", testDKIMDomain, v.Domain)
+		}
+	}
+
+	selectors := signatureSelectors(t, signed)
+	if len(selectors) != len(verifications) {
+		t.Fatalf("%d signatures, but %d verifications", len(selectors), len(verifications))
+	}
+	return selectors
+}
+
+// signatureSelectors returns the s= tags of the message's DKIM-Signature headers, sorted
+func signatureSelectors(t *testing.T, signed []byte) []string {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(bytes.NewReader(signed))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var selectors []string
+	for _, header := range msg.Header["Dkim-Signature"] {
+		for _, tag := range strings.Split(header, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(tag), "=")
+			if ok && name == "s" {
+				selectors = append(selectors, strings.Join(strings.Fields(value), ""))
+			}
+		}
+	}
+	sort.Strings(selectors)
+
+	return selectors
+}
+
+func TestDKIMTransportSignsVerifiably(t *testing.T) {
+	tests := []struct {
+		name string
+		key  testDKIMKey
+	}{
+		{"rsa", generateRSAKey(t, "rsa-2025")},
+		{"ed25519", generateEd25519Key(t, "ed-2025")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed, err := signWith(t, []config.DKIMKey{{Selector: tt.key.selector, KeyFile: writeKeyFile(t, tt.key)}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			selectors := verifiedSelectors(t, signed, tt.key)
+			if !slices.Equal(selectors, []string{tt.key.selector}) {
+				t.Fatalf("expected a signature by %s, got %v", tt.key.selector, selectors)
+			}
+		})
+	}
+}
+
+func TestDKIMTransportRotation(t *testing.T) {
+	now := time.Now()
+	old := generateRSAKey(t, "2025-01")
+	next := generateEd25519Key(t, "2025-07")
+	future := generateRSAKey(t, "2026-01")
+
+	tests := []struct {
+		name      string
+		keys      []config.DKIMKey
+		published []testDKIMKey
+		expected  []string
+	}{
+		{
+			name: "overlap signs with both keys",
+			keys: []config.DKIMKey{
+				{Selector: old.selector, KeyFile: writeKeyFile(t, old), ValidUntil: now.Add(time.Hour)},
+				{Selector: next.selector, KeyFile: writeKeyFile(t, next), ValidFrom: now.Add(-time.Hour)},
+			},
+			published: []testDKIMKey{old, next},
+			expected: []string{old.selector, next.selector},
+		},
+		{
+			name: "expired and future keys don't sign",
+			keys: []config.DKIMKey{
+				{Selector: old.selector, KeyFile: writeKeyFile(t, old), ValidUntil: now.Add(-time.Hour)},
+				{Selector: next.selector, KeyFile: writeKeyFile(t, next), ValidFrom: now.Add(-time.Hour)},
+				{Selector: future.selector, KeyFile: writeKeyFile(t, future), ValidFrom: now.Add(time.Hour)},
+			},
+			published: []testDKIMKey{next},
+			expected: []string{next.selector},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed, err := signWith(t, tt.keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			selectors := verifiedSelectors(t, signed, tt.published...)
+			expected := slices.Sorted(slices.Values(tt.expected))
+			if !slices.Equal(selectors, expected) {
+				t.Fatalf("expected signatures by %v, got %v", expected, selectors)
+			}
+		})
+	}
+}
+
+func TestDKIMTransportFailsWithoutActiveKey(t *testing.T) {
+	key := generateEd25519Key(t, "2024-01")
+
+	_, err := signWith(t, []config.DKIMKey{{Selector: key.selector, KeyFile: writeKeyFile(t, key), ValidUntil: time.Now().Add(-time.Hour)}})
+	if !errors.Is(err, ErrNoActiveDKIMKey) {
+		t.Fatalf("expected ErrNoActiveDKIMKey, got %v", err)
+	}
+	if !IsPermanent(err) {
+		t.Fatal("expected a permanent error")
+	}
+}
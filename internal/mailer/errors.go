@@ -0,0 +1,28 @@
+package mailer
+
+import (
+	"errors"
+	"net/textproto"
+
+	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+)
+
+var (
+	ErrRecipientSuppressed = errors.New("recipient is on the suppression list")
+	ErrNoActiveDKIMKey = errors.New("no dkim key is active, check the keys' valid_from and valid_until")
+)
+
+// IsPermanent tells whether a send failure is permanent: SMTP 5xx replies and errors marked as permanent.
+// SMTP 4xx replies and network errors are temporary.
+func IsPermanent(err error) bool {
+	if rabbitmq.IsPermanent(err) {
+		return true
+	}
+
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return tpErr.Code >= 500
+	}
+
+	return false
+}
@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	MAIL_EVENT_BOUNCE = "bounce"
+	MAIL_EVENT_COMPLAINT = "complaint"
+
+	BOUNCE_TYPE_HARD = "hard"
+)
+
+// ProcessMailEvents suppresses addresses that hard-bounced or complained.
+// Soft bounces are temporary and only logged.
+func (m *Mailer) ProcessMailEvents() {
+	queue := rabbitmq.MAIL_EVENTS_QUEUE
+	worker := m.worker(queue)
+	worker.Run(m.cfg.Workers, m.cfg.Prefetch, func(msg amqp.Delivery) {
+		var event dto.MQMailEvent
+		if err := json.Unmarshal(msg.Body, &event); err != nil {
+			m.logger.Sugar().Errorf("Failed to unmarshal json in queue(%s): %s", queue, err.Error())
+			worker.Fail(msg, rabbitmq.Permanent(err))
+			return
+		}
+
+		if event.Email == "" {
+			m.logger.Sugar().Errorf("Mail event without email in queue(%s)", queue)
+			worker.Fail(msg, rabbitmq.Permanent(errors.New("mail event has no email")))
+			return
+		}
+
+		var reason string
+		switch {
+		case event.Type == MAIL_EVENT_COMPLAINT:
+			reason = model.SUPPRESSION_REASON_COMPLAINT
+		case event.Type == MAIL_EVENT_BOUNCE && event.BounceType == BOUNCE_TYPE_HARD:
+			reason = model.SUPPRESSION_REASON_HARD_BOUNCE
+		default:
+			m.logger.Sugar().Infof("Ignored mail event(%s/%s) for(%s): %s", event.Type, event.BounceType, event.Email, event.Detail)
+			msg.Ack(false)
+			return
+		}
+
+		if err := m.repo.Postgres.Suppression.Add(context.Background(), model.EmailSuppression{
+			Email: event.Email,
+			Reason: reason,
+			Detail: event.Detail,
+		}); err != nil {
+			m.logger.Sugar().Errorf("Failed to suppress(%s): %s", event.Email, err.Error())
+			worker.Fail(msg, err)
+			return
+		}
+
+		msg.Ack(false)
+
+		m.logger.Sugar().Infof("Suppressed(%s) because of %s: %s", event.Email, reason, event.Detail)
+	})
+}
@@ -0,0 +1,105 @@
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/config"
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+	"github.com/BloggingApp/notification-service/internal/repository/redisrepo"
+)
+
+const (
+	LIMIT_SCOPE_RECIPIENT = "recipient"
+	LIMIT_SCOPE_TYPE = "type"
+	LIMIT_SCOPE_GLOBAL = "global"
+)
+
+var ErrRateLimited = errors.New("mail rate limit exceeded")
+
+type rateLimitError struct {
+	scope      string
+	limit      config.MailLimit
+	retryAfter time.Time
+}
+
+func (e *rateLimitError) Error() string {
+	return fmt.Sprintf("%s: %s limit of %d per %s", ErrRateLimited.Error(), e.scope, e.limit.Max, e.limit.Window)
+}
+
+func (e *rateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// hitLimit counts a mail in the limit's current window and fails once the window is full.
+// Limits fail open: when Redis is unavailable mails are not held back.
+func (m *Mailer) hitLimit(ctx context.Context, scope string, key string, limit config.MailLimit) error {
+	exceeded, windowEnd, err := redisrepo.HitLimit(m.rdb, ctx, func(windowStart int64) string {
+		return redisrepo.MailLimitKey(key, windowStart)
+	}, limit.Max, limit.Window)
+	if err != nil {
+		m.logger.Sugar().Errorf("Failed to check %s mail limit: %s", scope, err.Error())
+	}
+	if !exceeded {
+		return nil
+	}
+
+	return &rateLimitError{
+		scope: scope,
+		limit: limit,
+		retryAfter: windowEnd,
+	}
+}
+
+// checkRecipientLimits applies the limits of mails to the address
+func (m *Mailer) checkRecipientLimits(ctx context.Context, typeName string, recipient string) error {
+	recipient = strings.ToLower(recipient)
+
+	if err := m.hitLimit(ctx, LIMIT_SCOPE_TYPE, LIMIT_SCOPE_TYPE+":"+typeName+":"+recipient, m.cfg.TypeLimits[typeName]); err != nil {
+		return err
+	}
+
+	return m.hitLimit(ctx, LIMIT_SCOPE_RECIPIENT, LIMIT_SCOPE_RECIPIENT+":"+recipient, m.cfg.RecipientLimit)
+}
+
+// checkGlobalLimit applies the throughput limit of all replicas. Unlike recipient limits
+// it is not the recipient's fault, so the message is postponed instead of being dropped.
+func (m *Mailer) checkGlobalLimit(ctx context.Context) error {
+	return m.hitLimit(ctx, LIMIT_SCOPE_GLOBAL, LIMIT_SCOPE_GLOBAL, m.cfg.GlobalLimit)
+}
+
+// dropRateLimited records the dropped mail and tells the producer about it
+func (m *Mailer) dropRateLimited(ctx context.Context, typeName string, recipient string, attempt int, rlErr *rateLimitError) {
+	m.logger.Sugar().Warnf("Dropped %s mail to(%s): %s", typeName, recipient, rlErr.Error())
+
+	if err := m.repo.Postgres.EmailAudit.Create(ctx, model.EmailAuditRecord{
+		Type: typeName,
+		Recipient: recipient,
+		Status: model.EMAIL_STATUS_RATE_LIMITED,
+		Detail: rlErr.Error(),
+		Attempt: attempt,
+	}); err != nil {
+		m.logger.Sugar().Errorf("Failed to record dropped %s mail to(%s): %s", typeName, recipient, err.Error())
+	}
+
+	eventJSON, err := json.Marshal(dto.MQMailRateLimited{
+		Type: typeName,
+		Email: recipient,
+		Scope: rlErr.scope,
+		RetryAfter: rlErr.retryAfter,
+	})
+	if err != nil {
+		m.logger.Sugar().Errorf("Failed to marshal rate limited event: %s", err.Error())
+		return
+	}
+
+	if err := m.rabbitmq.PublishToQueue(rabbitmq.MAIL_RATE_LIMITED_QUEUE, eventJSON); err != nil {
+		m.logger.Sugar().Errorf("Failed to publish rate limited event for(%s): %s", recipient, err.Error())
+	}
+}
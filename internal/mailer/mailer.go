@@ -1,129 +1,283 @@
 package mailer
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"net/smtp"
-	"os"
+	"maps"
+	"net/mail"
+	"net/url"
+	"slices"
 	"time"
 
-	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/config"
 	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+	"github.com/BloggingApp/notification-service/internal/repository"
+	"github.com/BloggingApp/notification-service/internal/unsubscribe"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	REGISTRATION_CODE_TEMPLATE = "registration_code"
+	SIGNIN_CODE_TEMPLATE = "signin_code"
+	DIGEST_TEMPLATE = "digest"
+	PASSWORD_RESET_TEMPLATE = "password_reset"
+	EMAIL_CHANGE_CONFIRM_TEMPLATE = "email_change_confirm"
+	EMAIL_CHANGE_NOTICE_TEMPLATE = "email_change_notice"
+	NEW_DEVICE_SIGNIN_TEMPLATE = "new_device_signin"
+
+	DEFAULT_RETRY_MAX_ATTEMPTS = 5
+	DEFAULT_RETRY_INITIAL_DELAY = time.Second * 10
+
+	// indexes of the message's mails earlier attempts sent or dropped, so a retry skips them
+	HANDLED_MAILS_HEADER = "x-handled-mails"
 )
 
 type Mailer struct {
 	logger *zap.Logger
 	rabbitmq *rabbitmq.MQConn
-
-	from string
-	pass string
-	host string
-	port string
+	rdb *redis.Client
+	transport Transport
+	repo *repository.Repository
+	cfg config.MailerConfig
+	templates map[string]*mailTemplate
+	limiter *rate.Limiter
+	unsubscribeSigner *unsubscribe.Signer
 }
 
-func New(logger *zap.Logger, rabbitmq *rabbitmq.MQConn) *Mailer {
+func New(logger *zap.Logger, rabbitmq *rabbitmq.MQConn, rdb *redis.Client, transport Transport, repo *repository.Repository, unsubscribeSigner *unsubscribe.Signer, cfg config.MailerConfig) *Mailer {
+	templates, err := parseTemplates(emailTemplateNames()...)
+	if err != nil {
+		panic(err)
+	}
+
 	return &Mailer{
 		logger: logger,
 		rabbitmq: rabbitmq,
-		from: os.Getenv("FROM"),
-		pass: os.Getenv("PASS"),
-		host: os.Getenv("HOST"),
-		port: os.Getenv("PORT"),
+		rdb: rdb,
+		transport: transport,
+		repo: repo,
+		cfg: cfg,
+		templates: templates,
+		limiter: newLimiter(cfg.RateLimit, cfg.RateBurst),
+		unsubscribeSigner: unsubscribeSigner,
 	}
 }
 
-func (m *Mailer) StartProcessing() {
-	go m.ProcessRegistrationCodes()
-	go m.ProcessSignInCodes()
+func newLimiter(perSecond float64, burst int) *rate.Limiter {
+	if perSecond <= 0 {
+		return rate.NewLimiter(rate.Inf, 0)
+	}
+
+	return rate.NewLimiter(rate.Limit(perSecond), max(burst, 1))
+}
+
+// compose renders the template into a message addressed to a single recipient
+func (m *Mailer) compose(to string, subject string, templateName string, data any) (*Message, error) {
+	return m.composeWithLayout(to, templateName, layoutData{
+		AppName: m.cfg.AppName,
+		PublicURL: m.cfg.PublicURL,
+		Subject: subject,
+		Data: data,
+	})
 }
 
-func (m *Mailer) ProcessRegistrationCodes() {
-	queue := rabbitmq.REGISTRATION_CODE_MAIL_QUEUE
-	msgs, err := m.rabbitmq.Consume(queue)
+// composeUnsubscribable composes a non-transactional mail with an unsubscribe link
+// and the List-Unsubscribe headers for the user and email category
+func (m *Mailer) composeUnsubscribable(to string, userID uuid.UUID, category string, subject string, templateName string, data any) (*Message, error) {
+	query := url.Values{"token": {m.unsubscribeSigner.Sign(userID, category)}}.Encode()
+
+	msg, err := m.composeWithLayout(to, templateName, layoutData{
+		AppName: m.cfg.AppName,
+		PublicURL: m.cfg.PublicURL,
+		UnsubscribeURL: m.cfg.PublicURL + "/unsubscribe?" + query,
+		Subject: subject,
+		Data: data,
+	})
 	if err != nil {
-		m.logger.Sugar().Fatalf("Failed to start consuming(%s): %s", queue, err.Error())
+		return nil, err
 	}
 
-	for msg := range msgs {
-		var message dto.MQNotificateUserCode
-		if err := json.Unmarshal(msg.Body, &message); err != nil {
-			m.logger.Sugar().Errorf("Failed to unmarshal json in queue(%s): %s", queue, err.Error())
-			msg.Nack(false, false)
-			continue
-		}
+	msg.Headers["List-Unsubscribe"] = "<" + m.cfg.UnsubscribeURL + "?" + query + ">"
+	msg.Headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
 
-		if err := m.SendRegistrationCodeMail(message); err != nil {
-			m.logger.Sugar().Errorf("Failed to send mail to(%s): %s", message.Email, err.Error())
-			msg.Nack(false, true)
-			continue
-		}
+	return msg, nil
+}
 
-		msg.Ack(false)
+func (m *Mailer) composeWithLayout(to string, templateName string, data layoutData) (*Message, error) {
+	t, ok := m.templates[templateName]
+	if !ok {
+		return nil, fmt.Errorf("unknown mail template: %s", templateName)
+	}
 
-		m.logger.Sugar().Infof("Successfully sent registration code from queue(%s) to(%s)", queue, message.Email)
-		time.Sleep(time.Millisecond * 10)
+	html, text, err := t.render(data)
+	if err != nil {
+		return nil, err
 	}
-}
 
-func (m *Mailer) SendRegistrationCodeMail(input dto.MQNotificateUserCode) error {
-	subject := "Verify your email"
-	body := fmt.Sprintf("Your code:\n<b>%d</b>", input.Code)
+	msg := NewMessage(mail.Address{Name: m.cfg.FromName, Address: m.cfg.From}, []mail.Address{{Address: to}}, data.Subject)
+	msg.HTML = html
+	msg.Text = text
 
-	msg := []byte("Subject: " + subject + "\r\n" +
-	"MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n" +
-	"\r\n" + body)
+	return msg, nil
+}
 
-	auth := smtp.PlainAuth("", m.from, m.pass, m.host)
+func (m *Mailer) send(msg *Message) error {
+	for _, to := range msg.Recipients() {
+		suppressed, err := m.repo.Postgres.Suppression.IsSuppressed(context.Background(), to)
+		if err != nil {
+			return err
+		}
+		if suppressed {
+			return fmt.Errorf("%w: %s", ErrRecipientSuppressed, to)
+		}
+	}
 
-	if err := smtp.SendMail(m.host + ":" + m.port, auth, m.from, []string{input.Email}, msg); err != nil {
+	raw, err := msg.Bytes()
+	if err != nil {
 		return err
 	}
 
-	return nil
+	// shared by all workers of all queues, so it caps the whole mailer's throughput
+	if err := m.limiter.Wait(context.Background()); err != nil {
+		return err
+	}
+
+	return m.transport.Send(msg.From.Address, msg.Recipients(), raw)
 }
 
-func (m *Mailer) ProcessSignInCodes() {
-	queue := rabbitmq.SIGNIN_CODE_MAIL_QUEUE
-	msgs, err := m.rabbitmq.Consume(queue)
-	if err != nil {
-		m.logger.Sugar().Fatalf("Failed to start consuming(%s): %s", queue, err.Error())
+func (m *Mailer) retryPolicy() rabbitmq.RetryPolicy {
+	policy := rabbitmq.RetryPolicy{
+		MaxAttempts: m.cfg.RetryMaxAttempts,
+		InitialDelay: m.cfg.RetryInitialDelay,
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DEFAULT_RETRY_MAX_ATTEMPTS
+	}
+	if policy.InitialDelay <= 0 {
+		policy.InitialDelay = DEFAULT_RETRY_INITIAL_DELAY
 	}
 
-	for msg := range msgs {
-		var message dto.MQNotificateUserCode
-		if err := json.Unmarshal(msg.Body, &message); err != nil {
-			m.logger.Sugar().Errorf("Failed to unmarshal json in queue(%s): %s", queue, err.Error())
-			msg.Nack(false, false)
-			continue
-		}
+	return policy
+}
+
+// worker handles the queue's deliveries with the mailer's retry policy
+func (m *Mailer) worker(queue string) *rabbitmq.Worker {
+	return m.rabbitmq.NewWorker(queue, m.retryPolicy(), IsPermanent)
+}
 
-		if err := m.SendSignInCodeMail(message); err != nil {
-			m.logger.Sugar().Errorf("Failed to send mail to(%s): %s", message.Email, err.Error())
-			msg.Nack(false, true)
-			continue
+func (m *Mailer) StartProcessing() {
+	for _, t := range emailTypes {
+		go m.process(t)
+	}
+	go m.ProcessMailEvents()
+}
+
+// handledMails returns the indexes of the message's mails that earlier attempts sent or dropped
+func handledMails(msg amqp.Delivery) map[int]bool {
+	handled := map[int]bool{}
+	indexes, _ := msg.Headers[HANDLED_MAILS_HEADER].([]interface{})
+	for _, index := range indexes {
+		switch i := index.(type) {
+		case int32:
+			handled[int(i)] = true
+		case int64:
+			handled[int(i)] = true
 		}
+	}
 
-		msg.Ack(false)
+	return handled
+}
 
-		m.logger.Sugar().Infof("Successfully sent auth code from queue(%s) to(%s)", queue, message.Email)
-		time.Sleep(time.Millisecond * 10)
+// withHandledMails records the handled mails in the delivery's headers, which a retry carries over
+func withHandledMails(msg amqp.Delivery, handled map[int]bool) amqp.Delivery {
+	indexes := make([]interface{}, 0, len(handled))
+	for _, i := range slices.Sorted(maps.Keys(handled)) {
+		indexes = append(indexes, int32(i))
 	}
+
+	headers := make(amqp.Table, len(msg.Headers)+1)
+	maps.Copy(headers, msg.Headers)
+	headers[HANDLED_MAILS_HEADER] = indexes
+	msg.Headers = headers
+
+	return msg
 }
 
-func (m *Mailer) SendSignInCodeMail(input dto.MQNotificateUserCode) error {
-	subject := "Two-factor authentication"
-	body := fmt.Sprintf("Your code:\n<b>%d</b>", input.Code)
+// process consumes the email type's queue, sending every mail built from a message
+func (m *Mailer) process(t emailType) {
+	worker := m.worker(t.Queue)
+	worker.Run(m.cfg.Workers, m.cfg.Prefetch, func(msg amqp.Delivery) {
+		mails, err := t.build(msg.Body)
+		if err != nil {
+			m.logger.Sugar().Errorf("Failed to decode message in queue(%s): %s", t.Queue, err.Error())
+			worker.Fail(msg, rabbitmq.Permanent(err))
+			return
+		}
+
+		// checked before any mail is counted or sent, so postponing the message doesn't repeat anything
+		if err := m.checkGlobalLimit(context.Background()); err != nil {
+			m.logger.Sugar().Warnf("Postponed %s mail from queue(%s): %s", t.Name, t.Queue, err.Error())
+			worker.Postpone(msg)
+			return
+		}
+
+		// a retry builds the same mails again and skips the ones earlier attempts handled
+		handled := handledMails(msg)
+		for i, mail := range mails {
+			if handled[i] {
+				continue
+			}
 
-	msg := []byte("Subject: " + subject + "\r\n" +
-	"MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n" +
-	"\r\n" + body)
+			if err := m.checkRecipientLimits(context.Background(), t.Name, mail.To); err != nil {
+				var rlErr *rateLimitError
+				if errors.As(err, &rlErr) {
+					m.dropRateLimited(context.Background(), t.Name, mail.To, rabbitmq.Attempt(msg), rlErr)
+				}
+				handled[i] = true
+				continue
+			}
 
-	auth := smtp.PlainAuth("", m.from, m.pass, m.host)
+			messageID, err := m.sendMail(mail)
+			m.audit(t.Name, mail.To, messageID, rabbitmq.Attempt(msg), err)
+			if err != nil {
+				if errors.Is(err, ErrRecipientSuppressed) {
+					m.logger.Sugar().Infof("Dropped %s mail from queue(%s): %s", t.Name, t.Queue, err.Error())
+					handled[i] = true
+					continue
+				}
 
-	if err := smtp.SendMail(m.host + ":" + m.port, auth, m.from, []string{input.Email}, msg); err != nil {
-		return err
+				m.logger.Sugar().Errorf("Failed to send mail to(%s): %s", mail.To, err.Error())
+				worker.Fail(withHandledMails(msg, handled), err)
+				return
+			}
+			handled[i] = true
+
+			m.logger.Sugar().Infof("Successfully sent %s mail from queue(%s) to(%s)", t.Name, t.Queue, mail.To)
+		}
+
+		msg.Ack(false)
+	})
+}
+
+func (m *Mailer) composeMail(mail outgoingMail) (*Message, error) {
+	if mail.Category != "" {
+		return m.composeUnsubscribable(mail.To, mail.UserID, mail.Category, mail.Subject, mail.Template, mail.Data)
+	}
+
+	return m.compose(mail.To, mail.Subject, mail.Template, mail.Data)
+}
+
+// sendMail composes and sends the mail, returning its Message-ID once composed
+func (m *Mailer) sendMail(mail outgoingMail) (string, error) {
+	msg, err := m.composeMail(mail)
+	if err != nil {
+		return "", rabbitmq.Permanent(err)
 	}
 
-	return nil
+	return msg.MessageID, m.send(msg)
 }
@@ -0,0 +1,141 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/config"
+	"github.com/BloggingApp/notification-service/internal/repository"
+	"github.com/BloggingApp/notification-service/internal/repository/postgres"
+	"github.com/BloggingApp/notification-service/internal/unsubscribe"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// fakeSuppressions is the suppression list the mailer checks before sending
+type fakeSuppressions struct {
+	postgres.Suppression
+	emails map[string]bool
+}
+
+func (f *fakeSuppressions) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	return f.emails[email], nil
+}
+
+func newTestMailer(t *testing.T, suppressed ...string) (*Mailer, *MemoryTransport) {
+	t.Helper()
+
+	suppressions := &fakeSuppressions{emails: map[string]bool{}}
+	for _, email := range suppressed {
+		suppressions.emails[email] = true
+	}
+
+	signer, err := unsubscribe.NewSigner([]byte("0123456789abcdef0123456789abcdef"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport := NewMemoryTransport()
+	m := New(
+		zap.NewNop(),
+		nil,
+		nil,
+		transport,
+		&repository.Repository{Postgres: &postgres.PGRepo{Suppression: suppressions}},
+		signer,
+		config.MailerConfig{
+			From: "noreply@example.com",
+			FromName: "BloggingApp",
+			AppName: "BloggingApp",
+			PublicURL: "https://example.com",
+			UnsubscribeURL: "https://api.example.com/api/v1/unsubscribe",
+		},
+	)
+
+	return m, transport
+}
+
+func TestSendMailEveryEmailType(t *testing.T) {
+	for _, name := range EmailTypes() {
+		t.Run(name, func(t *testing.T) {
+			m, transport := newTestMailer(t)
+
+			mails, err := buildMails(name, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, outgoing := range mails {
+				if _, err := m.sendMail(outgoing); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			sent := transport.Messages()
+			if len(sent) != len(mails) {
+				t.Fatalf("expected %d sent messages, got %d", len(mails), len(sent))
+			}
+			for i, recorded := range sent {
+				if len(recorded.To) != 1 || recorded.To[0] != mails[i].To {
+					t.Fatalf("expected recipient %s, got %v", mails[i].To, recorded.To)
+				}
+
+				msg, err := mail.ReadMessage(bytes.NewReader(recorded.Msg))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject")); subject != mails[i].Subject {
+					t.Fatalf("expected subject %q, got %q", mails[i].Subject, subject)
+				}
+				if !strings.HasPrefix(msg.Header.Get("Content-Type"), "multipart/alternative") {
+					t.Fatalf("expected a multipart/alternative message, got %s", msg.Header.Get("Content-Type"))
+				}
+
+				unsubscribable := mails[i].Category != ""
+				if hasHeader := msg.Header.Get("List-Unsubscribe") != ""; hasHeader != unsubscribable {
+					t.Fatalf("expected List-Unsubscribe only on unsubscribable mails, got %v for category %q", hasHeader, mails[i].Category)
+				}
+			}
+		})
+	}
+}
+
+func TestSendMailSkipsSuppressedRecipients(t *testing.T) {
+	m, transport := newTestMailer(t, "jane@example.com")
+
+	mails, err := buildMails("signin_code", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.sendMail(mails[0]); !errors.Is(err, ErrRecipientSuppressed) {
+		t.Fatalf("expected ErrRecipientSuppressed, got %v", err)
+	}
+	if sent := transport.Messages(); len(sent) != 0 {
+		t.Fatalf("expected nothing sent, got %d messages", len(sent))
+	}
+}
+
+func TestHandledMailsSurviveTheHeaders(t *testing.T) {
+	msg := withHandledMails(amqp.Delivery{Headers: amqp.Table{"x-attempt": int32(2)}}, map[int]bool{1: true, 0: true})
+	if err := msg.Headers.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Headers["x-attempt"] != int32(2) {
+		t.Fatal("expected the other headers to be kept")
+	}
+
+	handled := handledMails(msg)
+	if len(handled) != 2 || !handled[0] || !handled[1] {
+		t.Fatalf("expected mails 0 and 1 handled, got %v", handled)
+	}
+	if handled := handledMails(amqp.Delivery{}); len(handled) != 0 {
+		t.Fatalf("expected no handled mails on a first attempt, got %v", handled)
+	}
+}
@@ -0,0 +1,134 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message is a composed email with HTML and plain text alternatives
+type Message struct {
+	From      mail.Address
+	To        []mail.Address
+	Subject   string
+	Date      time.Time
+	MessageID string
+	Headers   map[string]string // additional headers, e.g. List-Unsubscribe
+	Text      string
+	HTML      string
+}
+
+func NewMessage(from mail.Address, to []mail.Address, subject string) *Message {
+	return &Message{
+		From: from,
+		To: to,
+		Subject: subject,
+		Date: time.Now(),
+		MessageID: newMessageID(from.Address),
+		Headers: map[string]string{},
+	}
+}
+
+func newMessageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 && i < len(from)-1 {
+		domain = from[i+1:]
+	}
+
+	b := make([]byte, 16)
+	rand.Read(b)
+
+	return fmt.Sprintf("<%s.%d@%s>", hex.EncodeToString(b), time.Now().UnixNano(), domain)
+}
+
+func (msg *Message) Recipients() []string {
+	recipients := make([]string, 0, len(msg.To))
+	for _, to := range msg.To {
+		recipients = append(recipients, to.Address)
+	}
+	return recipients
+}
+
+// Bytes encodes the message as a multipart/alternative MIME message with CRLF line endings
+func (msg *Message) Bytes() ([]byte, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		// the preferred alternative goes last
+		{"text/plain; charset=UTF-8", msg.Text},
+		{"text/html; charset=UTF-8", msg.HTML},
+	}
+	for _, part := range parts {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(toCRLF(part.content))); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	to := make([]string, 0, len(msg.To))
+	for _, addr := range msg.To {
+		to = append(to, addr.String())
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", msg.From.String())
+	writeHeader(&buf, "To", strings.Join(to, ", "))
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	writeHeader(&buf, "Date", msg.Date.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", msg.MessageID)
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	keys := make([]string, 0, len(msg.Headers))
+	for k := range msg.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeHeader(&buf, k, msg.Headers[k])
+	}
+
+	writeHeader(&buf, "Content-Type", fmt.Sprintf("multipart/alternative; boundary=\"%s\"", mw.Boundary()))
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+func toCRLF(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
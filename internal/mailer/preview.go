@@ -0,0 +1,107 @@
+package mailer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+)
+
+var (
+	ErrUnknownEmailType = errors.New("unknown email type")
+	ErrInvalidEmailData = errors.New("invalid email data")
+)
+
+// Preview is a rendered mail as the recipient gets it, before DKIM signing
+type Preview struct {
+	To      string              `json:"to"`
+	Subject string              `json:"subject"`
+	Headers map[string][]string `json:"headers"`
+	HTML    string              `json:"html"`
+	Text    string              `json:"text"`
+}
+
+// buildMails builds the email type's mails from the data given as the queue message would carry it,
+// or from the type's sample when there is no data
+func buildMails(typeName string, data json.RawMessage) ([]outgoingMail, error) {
+	t, ok := findEmailType(typeName)
+	if !ok {
+		return nil, ErrUnknownEmailType
+	}
+
+	body := []byte(data)
+	if len(body) == 0 || string(body) == "null" {
+		sample, err := json.Marshal(t.Sample)
+		if err != nil {
+			return nil, err
+		}
+		body = sample
+	}
+
+	mails, err := t.build(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidEmailData, err.Error())
+	}
+
+	return mails, nil
+}
+
+func (m *Mailer) Preview(typeName string, data json.RawMessage) ([]*Preview, error) {
+	mails, err := buildMails(typeName, data)
+	if err != nil {
+		return nil, err
+	}
+
+	previews := make([]*Preview, 0, len(mails))
+	for _, outgoing := range mails {
+		msg, err := m.composeMail(outgoing)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidEmailData, err.Error())
+		}
+
+		raw, err := msg.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+
+		previews = append(previews, &Preview{
+			To: outgoing.To,
+			Subject: msg.Subject,
+			Headers: parsed.Header,
+			HTML: msg.HTML,
+			Text: msg.Text,
+		})
+	}
+
+	return previews, nil
+}
+
+// SendTest sends the email type's mails to the given address instead of their recipients,
+// through the configured transport. Rate limits don't apply, suppressions do.
+func (m *Mailer) SendTest(typeName string, to string, data json.RawMessage) ([]string, error) {
+	mails, err := buildMails(typeName, data)
+	if err != nil {
+		return nil, err
+	}
+
+	messageIDs := make([]string, 0, len(mails))
+	for _, outgoing := range mails {
+		outgoing.To = to
+		outgoing.Subject = "[Test] " + outgoing.Subject
+
+		messageID, err := m.sendMail(outgoing)
+		m.audit(typeName, to, messageID, 1, err)
+		if err != nil {
+			return nil, err
+		}
+
+		messageIDs = append(messageIDs, messageID)
+	}
+
+	return messageIDs, nil
+}
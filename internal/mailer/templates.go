@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+// layoutData is what every template is executed with. Email specific data is in Data.
+type layoutData struct {
+	AppName        string
+	PublicURL      string
+	UnsubscribeURL string // empty for transactional mails
+	Subject        string
+	Data           any
+}
+
+var templateFuncs = map[string]any{
+	"sub": func(a int64, b int) int64 {
+		return a - int64(b)
+	},
+}
+
+// mailTemplate is a pair of HTML and text bodies sharing the base layout
+type mailTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+func parseTemplate(name string) (*mailTemplate, error) {
+	html, err := htmltemplate.New("layout.html").Funcs(templateFuncs).ParseFS(templatesFS, "templates/layout.html", "templates/"+name+".html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s html template: %w", name, err)
+	}
+
+	text, err := texttemplate.New("layout.txt").Funcs(templateFuncs).ParseFS(templatesFS, "templates/layout.txt", "templates/"+name+".txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s text template: %w", name, err)
+	}
+
+	return &mailTemplate{html: html, text: text}, nil
+}
+
+func parseTemplates(names ...string) (map[string]*mailTemplate, error) {
+	templates := make(map[string]*mailTemplate, len(names))
+	for _, name := range names {
+		t, err := parseTemplate(name)
+		if err != nil {
+			return nil, err
+		}
+		templates[name] = t
+	}
+
+	return templates, nil
+}
+
+func (t *mailTemplate) render(data layoutData) (html string, text string, err error) {
+	var htmlBuf bytes.Buffer
+	if err := t.html.ExecuteTemplate(&htmlBuf, "layout.html", data); err != nil {
+		return "", "", err
+	}
+
+	var textBuf bytes.Buffer
+	if err := t.text.ExecuteTemplate(&textBuf, "layout.txt", data); err != nil {
+		return "", "", err
+	}
+
+	return htmlBuf.String(), textBuf.String(), nil
+}
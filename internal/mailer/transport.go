@@ -0,0 +1,49 @@
+package mailer
+
+import (
+	"fmt"
+
+	"github.com/BloggingApp/notification-service/internal/config"
+	"go.uber.org/zap"
+)
+
+const (
+	TRANSPORT_SMTP = "smtp"
+	TRANSPORT_FILE = "file"
+	TRANSPORT_MEMORY = "memory"
+	TRANSPORT_LOG = "log"
+)
+
+// Transport delivers an already composed message
+type Transport interface {
+	Send(from string, to []string, msg []byte) error
+}
+
+// NewTransport creates the configured transport, signing messages when DKIM keys are configured
+func NewTransport(logger *zap.Logger, cfg config.MailerConfig) (Transport, error) {
+	transport, err := newTransport(logger, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cfg.DKIM.Keys) == 0 {
+		return transport, nil
+	}
+
+	return NewDKIMTransport(transport, cfg.DKIM)
+}
+
+func newTransport(logger *zap.Logger, cfg config.MailerConfig) (Transport, error) {
+	switch cfg.Transport {
+	case TRANSPORT_SMTP, "":
+		return NewSMTPTransport(cfg.SMTP), nil
+	case TRANSPORT_FILE:
+		return NewFileTransport(cfg.FileDir)
+	case TRANSPORT_MEMORY:
+		return NewMemoryTransport(), nil
+	case TRANSPORT_LOG:
+		return NewLogTransport(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport: %s", cfg.Transport)
+	}
+}
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileTransport writes every message as an .eml file into a Maildir (tmp/, new/, cur/),
+// so local mail can be opened with any mail client instead of being sent.
+type FileTransport struct {
+	dir      string
+	hostname string
+	counter  atomic.Uint64
+}
+
+func NewFileTransport(dir string) (*FileTransport, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("file mail transport requires a directory")
+	}
+
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+
+	return &FileTransport{
+		dir: dir,
+		hostname: hostname,
+	}, nil
+}
+
+func (t *FileTransport) Send(from string, to []string, msg []byte) error {
+	name := fmt.Sprintf("%d.%d_%d.%s.eml", time.Now().UnixNano(), os.Getpid(), t.counter.Add(1), t.hostname)
+
+	// Maildir delivery: write to tmp/ and move to new/ when the file is complete
+	tmpPath := filepath.Join(t.dir, "tmp", name)
+	if err := os.WriteFile(tmpPath, msg, 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(t.dir, "new", name))
+}
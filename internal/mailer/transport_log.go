@@ -0,0 +1,17 @@
+package mailer
+
+import "go.uber.org/zap"
+
+// LogTransport only logs the envelope of every message
+type LogTransport struct {
+	logger *zap.Logger
+}
+
+func NewLogTransport(logger *zap.Logger) *LogTransport {
+	return &LogTransport{logger: logger}
+}
+
+func (t *LogTransport) Send(from string, to []string, msg []byte) error {
+	t.logger.Info("mail sent to log transport", zap.String("from", from), zap.Strings("to", to), zap.Int("size", len(msg)))
+	return nil
+}
@@ -0,0 +1,46 @@
+package mailer
+
+import "sync"
+
+type RecordedMessage struct {
+	From string
+	To   []string
+	Msg  []byte
+}
+
+// MemoryTransport keeps sent messages in memory. It's meant for tests.
+type MemoryTransport struct {
+	mu       sync.Mutex
+	messages []RecordedMessage
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+func (t *MemoryTransport) Send(from string, to []string, msg []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.messages = append(t.messages, RecordedMessage{
+		From: from,
+		To: append([]string(nil), to...),
+		Msg: append([]byte(nil), msg...),
+	})
+
+	return nil
+}
+
+func (t *MemoryTransport) Messages() []RecordedMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]RecordedMessage(nil), t.messages...)
+}
+
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.messages = nil
+}
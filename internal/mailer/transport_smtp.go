@@ -0,0 +1,199 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/config"
+)
+
+const (
+	SMTP_TLS_STARTTLS = "starttls"
+	SMTP_TLS_IMPLICIT = "implicit"
+	SMTP_TLS_NONE = "none"
+
+	DEFAULT_SMTP_MAX_CONNECTIONS = 4
+	DEFAULT_SMTP_TIMEOUT = time.Second * 15
+	// connections idle for longer are checked with NOOP before being reused
+	SMTP_IDLE_CHECK_AFTER = time.Second * 30
+)
+
+type smtpConn struct {
+	client   *smtp.Client
+	conn     net.Conn
+	lastUsed time.Time
+}
+
+// deadline bounds the next exchange with the server, so a stalled server can't hold a worker forever
+func (c *smtpConn) deadline(timeout time.Duration) {
+	c.conn.SetDeadline(time.Now().Add(timeout))
+}
+
+// SMTPTransport keeps a pool of authenticated SMTP connections and reuses them between sends.
+// Broken connections are dropped and redialed on the next send.
+type SMTPTransport struct {
+	cfg   config.SMTPConfig
+	idle  chan *smtpConn
+	slots chan struct{}
+}
+
+func NewSMTPTransport(cfg config.SMTPConfig) *SMTPTransport {
+	if cfg.MaxConnections <= 0 {
+		cfg.MaxConnections = DEFAULT_SMTP_MAX_CONNECTIONS
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DEFAULT_SMTP_TIMEOUT
+	}
+	if cfg.TLSMode == "" {
+		cfg.TLSMode = SMTP_TLS_STARTTLS
+	}
+
+	return &SMTPTransport{
+		cfg: cfg,
+		idle: make(chan *smtpConn, cfg.MaxConnections),
+		slots: make(chan struct{}, cfg.MaxConnections),
+	}
+}
+
+func (t *SMTPTransport) Send(from string, to []string, msg []byte) error {
+	// every connection takes a slot, so there are never more than MaxConnections of them
+	t.slots <- struct{}{}
+	defer func() { <-t.slots }()
+
+	conn, reused, err := t.acquire()
+	if err != nil {
+		return err
+	}
+
+	err = t.send(conn, from, to, msg)
+	if err != nil && reused && isConnError(err) {
+		// the server might have closed the idle connection, so try once more on a fresh one
+		conn.client.Close()
+		conn, err = t.dial()
+		if err != nil {
+			return err
+		}
+		err = t.send(conn, from, to, msg)
+	}
+
+	t.release(conn, err)
+	return err
+}
+
+func (t *SMTPTransport) acquire() (*smtpConn, bool, error) {
+	select {
+	case conn := <-t.idle:
+		conn.deadline(t.cfg.Timeout)
+		if time.Since(conn.lastUsed) < SMTP_IDLE_CHECK_AFTER || conn.client.Noop() == nil {
+			return conn, true, nil
+		}
+		conn.client.Close()
+	default:
+	}
+
+	conn, err := t.dial()
+	return conn, false, err
+}
+
+func (t *SMTPTransport) release(conn *smtpConn, sendErr error) {
+	conn.deadline(t.cfg.Timeout)
+	if sendErr != nil {
+		// a rejected transaction leaves the connection usable after RSET, anything else doesn't
+		if isConnError(sendErr) || conn.client.Reset() != nil {
+			conn.client.Close()
+			return
+		}
+	}
+
+	conn.lastUsed = time.Now()
+	select {
+	case t.idle <- conn:
+	default:
+		conn.client.Quit()
+	}
+}
+
+func (t *SMTPTransport) send(conn *smtpConn, from string, to []string, msg []byte) error {
+	conn.deadline(t.cfg.Timeout)
+
+	if err := conn.client.Mail(from); err != nil {
+		return err
+	}
+
+	for _, rcpt := range to {
+		if err := conn.client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := conn.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+func (t *SMTPTransport) dial() (*smtpConn, error) {
+	addr := net.JoinHostPort(t.cfg.Host, t.cfg.Port)
+	dialer := &net.Dialer{Timeout: t.cfg.Timeout}
+	tlsConfig := &tls.Config{ServerName: t.cfg.Host}
+
+	var conn net.Conn
+	var err error
+	if t.cfg.TLSMode == SMTP_TLS_IMPLICIT {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// covers the greeting, STARTTLS and AUTH
+	conn.SetDeadline(time.Now().Add(t.cfg.Timeout))
+
+	client, err := smtp.NewClient(conn, t.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if t.cfg.TLSMode == SMTP_TLS_STARTTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("smtp server %s doesn't support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	if t.cfg.Username != "" {
+		// sending unauthenticated would only get the mails rejected or marked as spam
+		if ok, _ := client.Extension("AUTH"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("smtp server %s doesn't support AUTH", addr)
+		}
+		if err := client.Auth(smtp.PlainAuth("", t.cfg.Username, t.cfg.Password, t.cfg.Host)); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	return &smtpConn{client: client, conn: conn, lastUsed: time.Now()}, nil
+}
+
+// isConnError tells whether the error broke the connection itself, as opposed to an SMTP reply
+func isConnError(err error) bool {
+	var tpErr *textproto.Error
+	return !errors.As(err, &tpErr)
+}
@@ -0,0 +1,86 @@
+package mailer
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/config"
+)
+
+// serveSMTP accepts connections and hands each to handle
+func serveSMTP(t *testing.T, handle func(conn net.Conn)) (string, string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return host, port
+}
+
+func TestSMTPTransportTimesOutOnStalledServer(t *testing.T) {
+	host, port := serveSMTP(t, func(conn net.Conn) {
+		// never greets
+		time.Sleep(time.Second * 5)
+	})
+
+	transport := NewSMTPTransport(config.SMTPConfig{Host: host, Port: port, TLSMode: SMTP_TLS_NONE, Timeout: time.Millisecond * 200})
+
+	start := time.Now()
+	err := transport.Send("noreply@example.com", []string{"jane@example.com"}, []byte("Subject: hi\r\n\r\nhi\r\n"))
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second*2 {
+		t.Fatalf("send took %s, the timeout is 200ms", elapsed)
+	}
+}
+
+func TestSMTPTransportRequiresAuthWhenConfigured(t *testing.T) {
+	host, port := serveSMTP(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		conn.Write([]byte("220 localhost ESMTP\r\n"))
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				// no AUTH advertised
+				conn.Write([]byte("250-localhost\r\n250 8BITMIME\r\n"))
+			case strings.HasPrefix(line, "QUIT"):
+				conn.Write([]byte("221 bye\r\n"))
+				return
+			default:
+				conn.Write([]byte("250 ok\r\n"))
+			}
+		}
+	})
+
+	transport := NewSMTPTransport(config.SMTPConfig{Host: host, Port: port, TLSMode: SMTP_TLS_NONE, Username: "user", Password: "pass", Timeout: time.Second})
+
+	err := transport.Send("noreply@example.com", []string{"jane@example.com"}, []byte("Subject: hi\r\n\r\nhi\r\n"))
+	if err == nil || !strings.Contains(err.Error(), "AUTH") {
+		t.Fatalf("expected an error about missing AUTH, got %v", err)
+	}
+}
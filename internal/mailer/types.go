@@ -0,0 +1,170 @@
+package mailer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+	"github.com/google/uuid"
+)
+
+var errMissingFields = errors.New("message is missing required fields")
+
+// outgoingMail is one mail built from a queue message
+type outgoingMail struct {
+	To       string
+	Subject  string
+	Template string
+	Data     any
+	// set for non-transactional mails, adds the unsubscribe link and headers
+	UserID   uuid.UUID
+	Category string
+}
+
+// emailType is a kind of email the mailer consumes from its own queue.
+// Adding a new one only takes a DTO, templates and an entry in emailTypes.
+type emailType struct {
+	Name      string
+	Queue     string
+	Templates []string
+	Sample    any // message used for previews when no data is given
+	// build decodes a queue message into the mails to send
+	build func(body []byte) ([]outgoingMail, error)
+}
+
+func newEmailType[T any](name string, queue string, templates []string, sample T, build func(input T) ([]outgoingMail, error)) emailType {
+	return emailType{
+		Name: name,
+		Queue: queue,
+		Templates: templates,
+		Sample: sample,
+		build: func(body []byte) ([]outgoingMail, error) {
+			var input T
+			if err := json.Unmarshal(body, &input); err != nil {
+				return nil, err
+			}
+
+			return build(input)
+		},
+	}
+}
+
+var sampleTime = time.Date(2025, time.January, 6, 9, 30, 0, 0, time.UTC)
+
+var emailTypes = []emailType{
+	newEmailType("registration_code", rabbitmq.REGISTRATION_CODE_MAIL_QUEUE, []string{REGISTRATION_CODE_TEMPLATE}, dto.MQNotificateUserCode{Email: "jane@example.com", Code: 123456}, func(input dto.MQNotificateUserCode) ([]outgoingMail, error) {
+		if input.Email == "" {
+			return nil, errMissingFields
+		}
+
+		return []outgoingMail{{To: input.Email, Subject: "Verify your email", Template: REGISTRATION_CODE_TEMPLATE, Data: input}}, nil
+	}),
+	newEmailType("signin_code", rabbitmq.SIGNIN_CODE_MAIL_QUEUE, []string{SIGNIN_CODE_TEMPLATE}, dto.MQNotificateUserCode{Email: "jane@example.com", Code: 123456}, func(input dto.MQNotificateUserCode) ([]outgoingMail, error) {
+		if input.Email == "" {
+			return nil, errMissingFields
+		}
+
+		return []outgoingMail{{To: input.Email, Subject: "Two-factor authentication", Template: SIGNIN_CODE_TEMPLATE, Data: input}}, nil
+	}),
+	newEmailType("password_reset", rabbitmq.PASSWORD_RESET_MAIL_QUEUE, []string{PASSWORD_RESET_TEMPLATE}, dto.MQPasswordReset{
+		Email: "jane@example.com",
+		Username: "jane",
+		ResetURL: "https://example.com/reset-password?token=sample",
+		ExpiresAt: sampleTime.Add(time.Hour),
+	}, func(input dto.MQPasswordReset) ([]outgoingMail, error) {
+		if input.Email == "" || input.ResetURL == "" {
+			return nil, errMissingFields
+		}
+
+		return []outgoingMail{{To: input.Email, Subject: "Reset your password", Template: PASSWORD_RESET_TEMPLATE, Data: input}}, nil
+	}),
+	// the new address confirms the change, the old one is told about it in case the account was taken over
+	newEmailType("email_change", rabbitmq.EMAIL_CHANGE_MAIL_QUEUE, []string{EMAIL_CHANGE_CONFIRM_TEMPLATE, EMAIL_CHANGE_NOTICE_TEMPLATE}, dto.MQEmailChange{
+		OldEmail: "jane@example.com",
+		NewEmail: "jane.doe@example.com",
+		Username: "jane",
+		ConfirmURL: "https://example.com/confirm-email?token=sample",
+		ExpiresAt: sampleTime.Add(time.Hour * 24),
+	}, func(input dto.MQEmailChange) ([]outgoingMail, error) {
+		if input.OldEmail == "" || input.NewEmail == "" || input.ConfirmURL == "" {
+			return nil, errMissingFields
+		}
+
+		return []outgoingMail{
+			{To: input.NewEmail, Subject: "Confirm your new email", Template: EMAIL_CHANGE_CONFIRM_TEMPLATE, Data: input},
+			{To: input.OldEmail, Subject: "Your email is being changed", Template: EMAIL_CHANGE_NOTICE_TEMPLATE, Data: input},
+		}, nil
+	}),
+	newEmailType("new_device_signin", rabbitmq.NEW_DEVICE_SIGNIN_MAIL_QUEUE, []string{NEW_DEVICE_SIGNIN_TEMPLATE}, dto.MQNewDeviceSignIn{
+		Email: "jane@example.com",
+		Username: "jane",
+		IP: "203.0.113.7",
+		UserAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0",
+		SignedInAt: sampleTime,
+	}, func(input dto.MQNewDeviceSignIn) ([]outgoingMail, error) {
+		if input.Email == "" {
+			return nil, errMissingFields
+		}
+
+		return []outgoingMail{{To: input.Email, Subject: "New sign-in to your account", Template: NEW_DEVICE_SIGNIN_TEMPLATE, Data: input}}, nil
+	}),
+	newEmailType("digest", rabbitmq.DIGEST_MAIL_QUEUE, []string{DIGEST_TEMPLATE}, dto.MQDigest{
+		UserID: uuid.Nil,
+		Email: "jane@example.com",
+		Username: "jane",
+		Frequency: model.DIGEST_FREQUENCY_DAILY,
+		TotalUnread: 3,
+		Notifications: []dto.MQDigestItem{
+			{Type: "new-post", Content: "john published a new post: Hello, world", CreatedAt: sampleTime.Add(-time.Hour)},
+			{Type: "follow", Content: "alice started following you", CreatedAt: sampleTime.Add(-time.Hour * 5)},
+		},
+	}, func(input dto.MQDigest) ([]outgoingMail, error) {
+		if input.Email == "" {
+			return nil, errMissingFields
+		}
+
+		subject := fmt.Sprintf("Your %s digest: %d unread notifications", input.Frequency, input.TotalUnread)
+		if input.TotalUnread == 1 {
+			subject = fmt.Sprintf("Your %s digest: 1 unread notification", input.Frequency)
+		}
+
+		return []outgoingMail{{
+			To: input.Email,
+			Subject: subject,
+			Template: DIGEST_TEMPLATE,
+			Data: input,
+			UserID: input.UserID,
+			Category: model.EMAIL_CATEGORY_DIGEST,
+		}}, nil
+	}),
+}
+
+func emailTemplateNames() []string {
+	var names []string
+	for _, t := range emailTypes {
+		names = append(names, t.Templates...)
+	}
+	return names
+}
+
+func findEmailType(name string) (emailType, bool) {
+	for _, t := range emailTypes {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return emailType{}, false
+}
+
+// EmailTypes returns the names of the email types the mailer sends
+func EmailTypes() []string {
+	names := make([]string, 0, len(emailTypes))
+	for _, t := range emailTypes {
+		names = append(names, t.Name)
+	}
+	return names
+}
@@ -0,0 +1,26 @@
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+var (
+	// raw HTML in the source is dropped by goldmark unless html.WithUnsafe is set
+	renderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+	policy   = bluemonday.UGCPolicy()
+)
+
+// ToSafeHTML renders Markdown source to HTML and sanitizes the result,
+// so it can be embedded by clients as is.
+func ToSafeHTML(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := renderer.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+
+	return policy.Sanitize(buf.String()), nil
+}
@@ -0,0 +1,187 @@
+package mobilepush
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	APNS_PRODUCTION_BASE_URL = "https://api.push.apple.com"
+	APNS_SANDBOX_BASE_URL = "https://api.sandbox.push.apple.com"
+
+	// APNs rejects tokens older than an hour and refreshing more often than every 20 minutes
+	APNS_TOKEN_REFRESH = time.Minute * 50
+)
+
+type APNsConfig struct {
+	BaseURL string // APNS_PRODUCTION_BASE_URL when empty
+	KeyFile string // .p8 signing key from the Apple developer account
+	KeyID   string
+	TeamID  string
+	Topic   string // the app's bundle ID
+}
+
+// APNsProvider sends through the APNs HTTP/2 API with token-based authentication
+type APNsProvider struct {
+	httpClient *http.Client
+	baseURL    string
+	keyID      string
+	teamID     string
+	topic      string
+	key        *ecdsa.PrivateKey
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+func NewAPNsProvider(httpClient *http.Client, cfg APNsConfig) (*APNsProvider, error) {
+	keyPEM, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read apns key: %w", err)
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("apns key file has no PEM block")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse apns key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("apns key must be an ECDSA P-256 key")
+	}
+
+	p := &APNsProvider{
+		httpClient: httpClient,
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		keyID: cfg.KeyID,
+		teamID: cfg.TeamID,
+		topic: cfg.Topic,
+		key: key,
+	}
+	if p.baseURL == "" {
+		p.baseURL = APNS_PRODUCTION_BASE_URL
+	}
+
+	return p, nil
+}
+
+func (p *APNsProvider) providerToken() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Since(p.issuedAt) < APNS_TOKEN_REFRESH {
+		return p.token, nil
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": p.teamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = p.keyID
+
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		return "", err
+	}
+
+	p.token = signed
+	p.issuedAt = now
+
+	return p.token, nil
+}
+
+type apsAlert struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type aps struct {
+	Alert apsAlert `json:"alert"`
+	Badge *int     `json:"badge,omitempty"`
+	Sound string   `json:"sound"`
+}
+
+func (p *APNsProvider) Send(ctx context.Context, msg Message) error {
+	token, err := p.providerToken()
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]any{
+		"aps": aps{
+			Alert: apsAlert{Title: msg.Title, Body: msg.Body},
+			Badge: msg.Badge,
+			Sound: "default",
+		},
+	}
+	for k, v := range msg.Data {
+		if k != "aps" {
+			payload[k] = v
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/3/device/"+url.PathEscape(msg.Token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("authorization", "bearer "+token)
+	req.Header.Set("apns-topic", p.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+	if msg.CollapseKey != "" {
+		req.Header.Set("apns-collapse-id", msg.CollapseKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var apnsErr struct {
+		Reason string `json:"reason"`
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	json.Unmarshal(respBody, &apnsErr)
+
+	switch {
+	case resp.StatusCode == http.StatusGone:
+		return ErrInvalidToken
+	case apnsErr.Reason == "BadDeviceToken" || apnsErr.Reason == "Unregistered" || apnsErr.Reason == "DeviceTokenNotForTopic":
+		return ErrInvalidToken
+	case apnsErr.Reason == "ExpiredProviderToken":
+		p.mu.Lock()
+		p.token = ""
+		p.mu.Unlock()
+	}
+
+	return fmt.Errorf("apns responded with %d: %s", resp.StatusCode, apnsErr.Reason)
+}
@@ -0,0 +1,210 @@
+package mobilepush
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// apnsRequest is a request the APNs mock got
+type apnsRequest struct {
+	path   string
+	header http.Header
+	body   map[string]any
+}
+
+// apnsService stands in for APNs, answering every request with status and reason
+func apnsService(t *testing.T, status int, reason string) (*APNsProvider, *ecdsa.PrivateKey, func() []apnsRequest) {
+	t.Helper()
+
+	var (
+		mu       sync.Mutex
+		requests []apnsRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(raw, &body); err != nil {
+			t.Errorf("body isn't json: %s", err)
+		}
+		mu.Lock()
+		requests = append(requests, apnsRequest{path: r.URL.Path, header: r.Header.Clone(), body: body})
+		mu.Unlock()
+
+		w.WriteHeader(status)
+		if reason != "" {
+			json.NewEncoder(w).Encode(map[string]string{"reason": reason})
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "AuthKey.p8")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewAPNsProvider(srv.Client(), APNsConfig{
+		BaseURL: srv.URL,
+		KeyFile: keyFile,
+		KeyID: "KEY1234567",
+		TeamID: "TEAM123456",
+		Topic: "com.example.blogging",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return provider, key, func() []apnsRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]apnsRequest{}, requests...)
+	}
+}
+
+func TestAPNsSendsCollapseIDAndBadge(t *testing.T) {
+	provider, key, requests := apnsService(t, http.StatusOK, "")
+
+	badge := 5
+	if err := provider.Send(context.Background(), Message{
+		Token: "device-token",
+		Title: "New follower",
+		Body: "jane followed you",
+		Data: map[string]string{"type": "new_follower", "aps": "ignored"},
+		CollapseKey: "follows",
+		Badge: &badge,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := requests()
+	if len(reqs) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(reqs))
+	}
+	req := reqs[0]
+
+	if req.path != "/3/device/device-token" {
+		t.Fatalf("expected the device path, got %s", req.path)
+	}
+	expectedHeaders := map[string]string{
+		"apns-topic": "com.example.blogging",
+		"apns-push-type": "alert",
+		"apns-collapse-id": "follows",
+	}
+	for name, expected := range expectedHeaders {
+		if got := req.header.Get(name); got != expected {
+			t.Fatalf("expected %s header %q, got %q", name, expected, got)
+		}
+	}
+
+	aps, _ := req.body["aps"].(map[string]any)
+	if aps["badge"] != float64(5) {
+		t.Fatalf("expected badge 5, got %v", aps["badge"])
+	}
+	if alert, _ := aps["alert"].(map[string]any); alert["title"] != "New follower" || alert["body"] != "jane followed you" {
+		t.Fatalf("expected the alert, got %v", aps["alert"])
+	}
+	if req.body["type"] != "new_follower" {
+		t.Fatalf("expected the data next to aps, got %v", req.body)
+	}
+
+	// the provider token is an ES256 JWT of the team, naming the key
+	bearer, ok := strings.CutPrefix(req.header.Get("authorization"), "bearer ")
+	if !ok {
+		t.Fatalf("expected a bearer token, got %q", req.header.Get("authorization"))
+	}
+	token, err := jwt.Parse(bearer, func(token *jwt.Token) (interface{}, error) {
+		return &key.PublicKey, nil
+	}, jwt.WithValidMethods([]string{"ES256"}), jwt.WithIssuedAt())
+	if err != nil {
+		t.Fatalf("invalid provider token: %s", err)
+	}
+	if token.Header["kid"] != "KEY1234567" {
+		t.Fatalf("expected kid KEY1234567, got %v", token.Header["kid"])
+	}
+	if iss, _ := token.Claims.GetIssuer(); iss != "TEAM123456" {
+		t.Fatalf("expected issuer TEAM123456, got %q", iss)
+	}
+}
+
+func TestAPNsLeavesBadgeAndCollapseIDOutWhenUnset(t *testing.T) {
+	provider, _, requests := apnsService(t, http.StatusOK, "")
+
+	if err := provider.Send(context.Background(), Message{Token: "device-token", Title: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	req := requests()[0]
+	if _, ok := req.header["Apns-Collapse-Id"]; ok {
+		t.Fatal("expected no apns-collapse-id header")
+	}
+	if aps, _ := req.body["aps"].(map[string]any); aps["badge"] != nil {
+		t.Fatalf("expected no badge, got %v", aps["badge"])
+	}
+}
+
+func TestAPNsReportsInvalidTokens(t *testing.T) {
+	tests := []struct {
+		status  int
+		reason  string
+		invalid bool
+	}{
+		{http.StatusGone, "Unregistered", true},
+		{http.StatusGone, "", true},
+		{http.StatusBadRequest, "BadDeviceToken", true},
+		{http.StatusBadRequest, "DeviceTokenNotForTopic", true},
+		{http.StatusBadRequest, "PayloadTooLarge", false},
+		{http.StatusTooManyRequests, "TooManyRequests", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status)+" "+tt.reason, func(t *testing.T) {
+			provider, _, _ := apnsService(t, tt.status, tt.reason)
+
+			err := provider.Send(context.Background(), Message{Token: "device-token", Title: "hi"})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if errors.Is(err, ErrInvalidToken) != tt.invalid {
+				t.Fatalf("expected invalid token %v, got %v", tt.invalid, err)
+			}
+		})
+	}
+}
+
+func TestAPNsRefreshesExpiredProviderToken(t *testing.T) {
+	provider, _, requests := apnsService(t, http.StatusForbidden, "ExpiredProviderToken")
+
+	for range 2 {
+		if err := provider.Send(context.Background(), Message{Token: "device-token", Title: "hi"}); err == nil || errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("expected a failure other than ErrInvalidToken, got %v", err)
+		}
+	}
+
+	reqs := requests()
+	if reqs[0].header.Get("authorization") == reqs[1].header.Get("authorization") {
+		t.Fatal("expected a new provider token after ExpiredProviderToken")
+	}
+}
@@ -0,0 +1,246 @@
+package mobilepush
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	FCM_DEFAULT_BASE_URL = "https://fcm.googleapis.com"
+	FCM_SCOPE = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+type FCMConfig struct {
+	BaseURL         string // FCM_DEFAULT_BASE_URL when empty
+	TokenURL        string // the service account's token_uri when empty
+	ProjectID       string // the service account's project_id when empty
+	CredentialsFile string // Google service account JSON
+}
+
+type serviceAccount struct {
+	ProjectID   string `json:"project_id"`
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// FCMProvider sends through the FCM HTTP v1 API, authorized by OAuth 2.0 tokens
+// of a service account
+type FCMProvider struct {
+	httpClient  *http.Client
+	baseURL     string
+	tokenURL    string
+	projectID   string
+	clientEmail string
+	key         *rsa.PrivateKey
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+func NewFCMProvider(httpClient *http.Client, cfg FCMConfig) (*FCMProvider, error) {
+	credentials, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fcm credentials: %w", err)
+	}
+
+	var account serviceAccount
+	if err := json.Unmarshal(credentials, &account); err != nil {
+		return nil, fmt.Errorf("failed to parse fcm credentials: %w", err)
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, errors.New("fcm credentials have no private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse fcm private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("fcm private key must be an RSA key")
+	}
+
+	p := &FCMProvider{
+		httpClient: httpClient,
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+		tokenURL: cfg.TokenURL,
+		projectID: cfg.ProjectID,
+		clientEmail: account.ClientEmail,
+		key: key,
+	}
+	if p.baseURL == "" {
+		p.baseURL = FCM_DEFAULT_BASE_URL
+	}
+	if p.tokenURL == "" {
+		p.tokenURL = account.TokenURI
+	}
+	if p.projectID == "" {
+		p.projectID = account.ProjectID
+	}
+
+	return p, nil
+}
+
+// token returns a cached access token, exchanging a new signed assertion for it shortly before it expires
+func (p *FCMProvider) token(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.accessToken != "" && time.Now().Add(time.Minute).Before(p.expiresAt) {
+		return p.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": p.clientEmail,
+		"scope": FCM_SCOPE,
+		"aud": p.tokenURL,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}).SignedString(p.key)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion": {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("fcm token endpoint responded with %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+
+	p.accessToken = token.AccessToken
+	p.expiresAt = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+
+	return p.accessToken, nil
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+	Android      fcmAndroid        `json:"android"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmAndroid struct {
+	CollapseKey  string                 `json:"collapse_key,omitempty"`
+	Notification fcmAndroidNotification `json:"notification"`
+}
+
+type fcmAndroidNotification struct {
+	NotificationCount *int   `json:"notification_count,omitempty"`
+	Tag               string `json:"tag,omitempty"` // replaces the shown notification with the same tag
+}
+
+func (p *FCMProvider) Send(ctx context.Context, msg Message) error {
+	accessToken, err := p.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]fcmMessage{
+		"message": {
+			Token: msg.Token,
+			Notification: fcmNotification{Title: msg.Title, Body: msg.Body},
+			Data: msg.Data,
+			Android: fcmAndroid{
+				CollapseKey: msg.CollapseKey,
+				Notification: fcmAndroidNotification{NotificationCount: msg.Badge, Tag: msg.CollapseKey},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/projects/"+url.PathEscape(p.projectID)+"/messages:send", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var fcmErr struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	json.Unmarshal(respBody, &fcmErr)
+
+	for _, detail := range fcmErr.Error.Details {
+		if detail.ErrorCode == "UNREGISTERED" {
+			return ErrInvalidToken
+		}
+	}
+	// a malformed token is rejected as an invalid argument
+	if resp.StatusCode == http.StatusNotFound || (fcmErr.Error.Status == "INVALID_ARGUMENT" && strings.Contains(fcmErr.Error.Message, "registration token")) {
+		return ErrInvalidToken
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		p.mu.Lock()
+		p.accessToken = ""
+		p.mu.Unlock()
+	}
+
+	return fmt.Errorf("fcm responded with %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+}
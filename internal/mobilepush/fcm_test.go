@@ -0,0 +1,228 @@
+package mobilepush
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// fcmService stands in for the OAuth token endpoint and the FCM API, answering sends with respond
+type fcmService struct {
+	mu            sync.Mutex
+	tokenRequests int
+	sends         []fcmRequest
+}
+
+type fcmRequest struct {
+	authorization string
+	message       map[string]any
+}
+
+func newFCMService(t *testing.T, respond func(w http.ResponseWriter)) (*fcmService, *FCMProvider) {
+	t.Helper()
+
+	fcm := &fcmService{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			fcm.mu.Lock()
+			fcm.tokenRequests++
+			fcm.mu.Unlock()
+			json.NewEncoder(w).Encode(map[string]any{"access_token": "access-token", "expires_in": 3600})
+		case "/v1/projects/test-project/messages:send":
+			body, _ := io.ReadAll(r.Body)
+			var payload struct {
+				Message map[string]any `json:"message"`
+			}
+			if err := json.Unmarshal(body, &payload); err != nil {
+				t.Errorf("send body isn't json: %s", err)
+			}
+			fcm.mu.Lock()
+			fcm.sends = append(fcm.sends, fcmRequest{authorization: r.Header.Get("Authorization"), message: payload.Message})
+			fcm.mu.Unlock()
+			respond(w)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials, err := json.Marshal(serviceAccount{
+		ProjectID: "test-project",
+		ClientEmail: "push@test-project.iam.gserviceaccount.com",
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI: srv.URL + "/token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialsFile := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(credentialsFile, credentials, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewFCMProvider(srv.Client(), FCMConfig{BaseURL: srv.URL, CredentialsFile: credentialsFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return fcm, provider
+}
+
+func (f *fcmService) requests() ([]fcmRequest, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]fcmRequest{}, f.sends...), f.tokenRequests
+}
+
+func fcmError(status int, body string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}
+}
+
+func TestFCMSendsCollapseKeyAndBadge(t *testing.T) {
+	fcm, provider := newFCMService(t, func(w http.ResponseWriter) {
+		io.WriteString(w, `{"name":"projects/test-project/messages/1"}`)
+	})
+
+	badge := 3
+	if err := provider.Send(context.Background(), Message{
+		Token: "device-token",
+		Title: "New follower",
+		Body: "jane followed you",
+		Data: map[string]string{"type": "new_follower"},
+		CollapseKey: "follows",
+		Badge: &badge,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	sends, _ := fcm.requests()
+	if len(sends) != 1 {
+		t.Fatalf("expected 1 send, got %d", len(sends))
+	}
+	if sends[0].authorization != "Bearer access-token" {
+		t.Fatalf("expected the access token, got %q", sends[0].authorization)
+	}
+
+	msg := sends[0].message
+	if msg["token"] != "device-token" {
+		t.Fatalf("expected the device token, got %v", msg["token"])
+	}
+	android, _ := msg["android"].(map[string]any)
+	if android["collapse_key"] != "follows" {
+		t.Fatalf("expected collapse_key follows, got %v", android["collapse_key"])
+	}
+	notification, _ := android["notification"].(map[string]any)
+	if notification["tag"] != "follows" {
+		t.Fatalf("expected tag follows, got %v", notification["tag"])
+	}
+	if notification["notification_count"] != float64(3) {
+		t.Fatalf("expected notification_count 3, got %v", notification["notification_count"])
+	}
+	if data, _ := msg["data"].(map[string]any); data["type"] != "new_follower" {
+		t.Fatalf("expected the data, got %v", msg["data"])
+	}
+}
+
+func TestFCMLeavesBadgeAndCollapseKeyOutWhenUnset(t *testing.T) {
+	fcm, provider := newFCMService(t, func(w http.ResponseWriter) {})
+
+	if err := provider.Send(context.Background(), Message{Token: "device-token", Title: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sends, _ := fcm.requests()
+	android, _ := sends[0].message["android"].(map[string]any)
+	if _, ok := android["collapse_key"]; ok {
+		t.Fatalf("expected no collapse_key, got %v", android)
+	}
+	notification, _ := android["notification"].(map[string]any)
+	if _, ok := notification["notification_count"]; ok {
+		t.Fatalf("expected no notification_count, got %v", notification)
+	}
+}
+
+func TestFCMReportsInvalidTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(w http.ResponseWriter)
+		invalid bool
+	}{
+		{
+			"unregistered",
+			fcmError(http.StatusNotFound, `{"error":{"code":404,"status":"NOT_FOUND","message":"Requested entity was not found.","details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"UNREGISTERED"}]}}`),
+			true,
+		},
+		{
+			"not found without details",
+			fcmError(http.StatusNotFound, `{}`),
+			true,
+		},
+		{
+			"malformed token",
+			fcmError(http.StatusBadRequest, `{"error":{"code":400,"status":"INVALID_ARGUMENT","message":"The registration token is not a valid FCM registration token"}}`),
+			true,
+		},
+		{
+			"other invalid argument",
+			fcmError(http.StatusBadRequest, `{"error":{"code":400,"status":"INVALID_ARGUMENT","message":"Invalid JSON payload received."}}`),
+			false,
+		},
+		{
+			"quota exceeded",
+			fcmError(http.StatusTooManyRequests, `{"error":{"code":429,"status":"RESOURCE_EXHAUSTED","details":[{"errorCode":"QUOTA_EXCEEDED"}]}}`),
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, provider := newFCMService(t, tt.respond)
+
+			err := provider.Send(context.Background(), Message{Token: "device-token", Title: "hi"})
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if errors.Is(err, ErrInvalidToken) != tt.invalid {
+				t.Fatalf("expected invalid token %v, got %v", tt.invalid, err)
+			}
+		})
+	}
+}
+
+func TestFCMRefreshesRejectedAccessToken(t *testing.T) {
+	fcm, provider := newFCMService(t, fcmError(http.StatusUnauthorized, `{"error":{"code":401,"status":"UNAUTHENTICATED"}}`))
+
+	for range 2 {
+		if err := provider.Send(context.Background(), Message{Token: "device-token", Title: "hi"}); err == nil || errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("expected a failure other than ErrInvalidToken, got %v", err)
+		}
+	}
+
+	if _, tokenRequests := fcm.requests(); tokenRequests != 2 {
+		t.Fatalf("expected a new access token after the 401, got %d token requests", tokenRequests)
+	}
+}
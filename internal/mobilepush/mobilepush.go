@@ -0,0 +1,28 @@
+package mobilepush
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	PLATFORM_ANDROID = "android"
+	PLATFORM_IOS = "ios"
+)
+
+// ErrInvalidToken means the device token is unknown to the provider or expired, it should be removed
+var ErrInvalidToken = errors.New("device token is invalid or unregistered")
+
+type Message struct {
+	Token       string
+	Title       string
+	Body        string
+	Data        map[string]string
+	CollapseKey string // a newer message with the same key replaces an undelivered one
+	Badge       *int   // app icon badge, nil leaves it as is
+}
+
+// Provider delivers messages to the devices of one platform
+type Provider interface {
+	Send(ctx context.Context, msg Message) error
+}
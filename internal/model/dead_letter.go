@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+type DeadLetterQueue struct {
+	Queue           string `json:"queue"`
+	DeadLetterQueue string `json:"dead_letter_queue"`
+	Messages        int    `json:"messages"`
+}
+
+type DeadLetter struct {
+	ID             string         `json:"id"`
+	Queue          string         `json:"queue"`
+	Error          string         `json:"error"`
+	Attempts       int            `json:"attempts"`
+	DeadLetteredAt *time.Time     `json:"dead_lettered_at"`
+	ContentType    string         `json:"content_type"`
+	Headers        map[string]any `json:"headers"`
+	Body           string         `json:"body"`
+}
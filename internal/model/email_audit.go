@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+const (
+	EMAIL_STATUS_SENT = "sent"
+	EMAIL_STATUS_FAILED = "failed" // temporary failure, retried later
+	EMAIL_STATUS_REJECTED = "rejected" // permanent failure
+	EMAIL_STATUS_SUPPRESSED = "suppressed"
+	EMAIL_STATUS_RATE_LIMITED = "rate_limited"
+)
+
+// EmailAuditRecord is one attempt to send a mail. It never holds the mail's content,
+// so codes and links stay out of the log.
+type EmailAuditRecord struct {
+	ID           int64     `json:"id"`
+	Type         string    `json:"type"`
+	Recipient    string    `json:"recipient"`
+	MessageID    string    `json:"message_id"`
+	Status       string    `json:"status"`
+	SMTPResponse string    `json:"smtp_response"`
+	Detail       string    `json:"detail"` // why the mail was not sent when it was dropped before the transport
+	Attempt      int       `json:"attempt"`
+	CreatedAt    time.Time `json:"created_at"`
+}
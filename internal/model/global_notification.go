@@ -7,10 +7,80 @@ import (
 )
 
 type GlobalNotification struct {
-	ID           int64     `json:"id"`
-	PosterID     uuid.UUID `json:"poster_id"`
-	Title        string    `json:"title"`
-	Content      string    `json:"content"`
-	ResourceLink string    `json:"resource_link"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID           int64                       `json:"id"`
+	PosterID     uuid.UUID                   `json:"poster_id"`
+	Title        string                      `json:"title"`
+	Content      string                      `json:"content"`      // Markdown source
+	ContentHTML  string                      `json:"content_html"` // sanitized HTML rendered from Content
+	ResourceLink string                      `json:"resource_link"`
+	Variant      string                      `json:"variant"`
+	Severity     string                      `json:"severity"`
+	Dismissible  bool                        `json:"dismissible"`
+	Audience     *GlobalNotificationAudience `json:"audience,omitempty"`
+	Status       string                      `json:"status"`
+	RevisionOf   *int64                      `json:"revision_of"` // the published notification this revision replaces the content of
+	SubmittedBy  *uuid.UUID                  `json:"submitted_by"`
+	ApprovedBy   *uuid.UUID                  `json:"approved_by"`
+	UpdatedBy    *uuid.UUID                  `json:"updated_by"` // who last edited the content
+	PublishedAt  *time.Time                  `json:"published_at"`
+	CreatedAt    time.Time                   `json:"created_at"`
+}
+
+const (
+	GLOBAL_NOTIFICATION_STATUS_DRAFT = "draft"
+	GLOBAL_NOTIFICATION_STATUS_PENDING_APPROVAL = "pending_approval"
+	GLOBAL_NOTIFICATION_STATUS_APPROVED = "approved"
+	GLOBAL_NOTIFICATION_STATUS_PUBLISHED = "published"
+)
+
+const (
+	GLOBAL_NOTIFICATION_ACTION_CREATED = "created"
+	GLOBAL_NOTIFICATION_ACTION_UPDATED = "updated"
+	GLOBAL_NOTIFICATION_ACTION_SUBMITTED = "submitted"
+	GLOBAL_NOTIFICATION_ACTION_APPROVED = "approved"
+	GLOBAL_NOTIFICATION_ACTION_REJECTED = "rejected"
+	GLOBAL_NOTIFICATION_ACTION_PUBLISHED = "published"
+	GLOBAL_NOTIFICATION_ACTION_DELETED = "deleted"
+	GLOBAL_NOTIFICATION_ACTION_REVISED = "revised"
+)
+
+// GlobalNotificationEvent is an audit trail entry of a global notification's state change.
+// Events are kept after the notification itself is deleted.
+type GlobalNotificationEvent struct {
+	ID             int64     `json:"id"`
+	NotificationID int64     `json:"notification_id"`
+	ActorID        uuid.UUID `json:"actor_id"`
+	Action         string    `json:"action"`
+	FromStatus     string    `json:"from_status"`
+	ToStatus       string    `json:"to_status"`
+	Comment        string    `json:"comment"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// GlobalNotificationAudience narrows a global notification down to a segment of users.
+// Every criterion that is set must match (they are AND-ed). A nil audience means everyone.
+type GlobalNotificationAudience struct {
+	UserIDs      []uuid.UUID `json:"user_ids,omitempty"`
+	CreatedAfter *time.Time  `json:"created_after,omitempty"`
+	MinFollowers *int        `json:"min_followers,omitempty"`
+	Locale       *string     `json:"locale,omitempty"`
+}
+
+func (a *GlobalNotificationAudience) IsEveryone() bool {
+	return a == nil || (len(a.UserIDs) == 0 && a.CreatedAfter == nil && a.MinFollowers == nil && a.Locale == nil)
+}
+
+type GlobalNotificationStats struct {
+	NotificationID int64                           `json:"notification_id"`
+	AudienceSize   int64                           `json:"audience_size"`
+	ReadCount      int64                           `json:"read_count"`
+	ReadRate       float64                         `json:"read_rate"`
+	Timeline       []*GlobalNotificationReadBucket `json:"timeline"`
+}
+
+type GlobalNotificationReadBucket struct {
+	Bucket          time.Time `json:"bucket"`
+	Reads           int64     `json:"reads"`
+	CumulativeReads int64     `json:"cumulative_reads"`
+	ReadRate        float64   `json:"read_rate"`
 }
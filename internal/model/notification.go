@@ -7,17 +7,33 @@ import (
 )
 
 type Notification struct {
-	ID         int64     `json:"id"`
-	Type       string    `json:"type"`
+	ID         int64      `json:"id"`
+	Type       string     `json:"type"`
+	ReceiverID uuid.UUID  `json:"receiver_id"`
+	Content    string     `json:"content"`
+	ResourceID string     `json:"resource_id"`
+	ReadAt     *time.Time `json:"read_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type NotificationDelivery struct {
 	ReceiverID uuid.UUID `json:"receiver_id"`
+	Type       string    `json:"type"`
 	Content    string    `json:"content"`
 	ResourceID string    `json:"resource_id"`
-	CreatedAt  time.Time `json:"created_at"`
+	Priority   string    `json:"priority"`
 }
 
-type NotificationDelivery struct {
-	ReceiverID uuid.UUID
-	Type       string
-	Content    string
-	ResourceID string
+// RoutingDecision records which channel a notification was delivered through and why
+type RoutingDecision struct {
+	ID         int64     `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	Type       string    `json:"type"`
+	ResourceID string    `json:"resource_id"`
+	Priority   string    `json:"priority"`
+	Channel    string    `json:"channel"`
+	Reason     string    `json:"reason"`
+	Online     bool      `json:"online"`
+	QuietHours bool      `json:"quiet_hours"`
+	CreatedAt  time.Time `json:"created_at"`
 }
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DIGEST_FREQUENCY_OFF = "off"
+	DIGEST_FREQUENCY_DAILY = "daily"
+	DIGEST_FREQUENCY_WEEKLY = "weekly"
+
+	// non-transactional email categories users can unsubscribe from
+	EMAIL_CATEGORY_DIGEST = "digest"
+)
+
+type NotificationPreferences struct {
+	UserID          uuid.UUID `json:"user_id"`
+	DigestFrequency string    `json:"digest_frequency"`
+	Timezone        string    `json:"timezone"` // IANA name, e.g. Europe/Kyiv
+	PushEnabled     bool      `json:"push_enabled"`
+	// local "15:04" times in Timezone, no push is sent between them; nil when there are no quiet hours
+	QuietHoursStart *string   `json:"quiet_hours_start"`
+	QuietHoursEnd   *string   `json:"quiet_hours_end"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func DefaultNotificationPreferences(userID uuid.UUID) *NotificationPreferences {
+	return &NotificationPreferences{
+		UserID: userID,
+		DigestFrequency: DIGEST_FREQUENCY_OFF,
+		Timezone: "UTC",
+		PushEnabled: true,
+	}
+}
+
+// DigestRecipient is a user who may get a digest in the current run
+type DigestRecipient struct {
+	UserID          uuid.UUID
+	Username        string
+	Email           string
+	DigestFrequency string
+	Timezone        string
+}
@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	PRESENCE_ONLINE = "online"
+	PRESENCE_IDLE = "idle" // connected, but the client reported the user inactive
+	PRESENCE_OFFLINE = "offline"
+)
+
+type Presence struct {
+	UserID   uuid.UUID  `json:"user_id"`
+	State    string     `json:"state"`
+	LastSeen *time.Time `json:"last_seen"` // nil when the user hasn't been seen for a long time
+}
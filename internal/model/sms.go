@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+const (
+	SMS_STATUS_QUEUED = "queued" // accepted by the gateway
+	SMS_STATUS_SENT = "sent"
+	SMS_STATUS_DELIVERED = "delivered"
+	SMS_STATUS_UNDELIVERED = "undelivered"
+	SMS_STATUS_FAILED = "failed" // temporary failure, retried later
+	SMS_STATUS_REJECTED = "rejected" // permanent failure
+	SMS_STATUS_RATE_LIMITED = "rate_limited"
+)
+
+// SMSMessage is one attempt to send a text message. Like email audit records
+// it never holds the message's content.
+type SMSMessage struct {
+	ID        int64     `json:"id"`
+	MessageID string    `json:"message_id"` // the gateway's ID, empty when it didn't accept the message
+	Type      string    `json:"type"`
+	Phone     string    `json:"phone"`
+	Status    string    `json:"status"`
+	Detail    string    `json:"detail"`
+	Attempt   int       `json:"attempt"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+const (
+	SUPPRESSION_REASON_HARD_BOUNCE = "hard_bounce"
+	SUPPRESSION_REASON_COMPLAINT = "complaint"
+)
+
+// EmailSuppression is an address no mail is sent to
+type EmailSuppression struct {
+	Email     string    `json:"email"`
+	Reason    string    `json:"reason"`
+	Detail    string    `json:"detail"` // diagnostic from the mail relay
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
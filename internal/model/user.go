@@ -1,10 +1,17 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type User struct {
 	ID          uuid.UUID `json:"id"`
 	Username    string    `json:"username"`
 	DisplayName *string   `json:"display_name"`
 	AvatarURL   *string   `json:"avatar_url"`
+	Email       *string   `json:"email"`
+	Locale      *string   `json:"locale"`
+	CreatedAt   time.Time `json:"created_at"`
 }
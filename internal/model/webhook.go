@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Webhook struct {
+	ID                  int64     `json:"id"`
+	UserID              uuid.UUID `json:"user_id"`
+	URL                 string    `json:"url"`
+	Secret              string    `json:"secret,omitempty"` // only shown when the webhook is created
+	Types               []string  `json:"types"`            // notification types delivered to the url
+	Enabled             bool      `json:"enabled"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	DisabledReason      string    `json:"disabled_reason,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one attempt of delivering an event
+type WebhookDelivery struct {
+	ID         int64     `json:"id"`
+	WebhookID  int64     `json:"webhook_id"`
+	EventID    uuid.UUID `json:"event_id"`
+	Type       string    `json:"type"`
+	Attempt    int       `json:"attempt"`
+	Success    bool      `json:"success"`
+	StatusCode *int      `json:"status_code"` // nil when there was no response
+	Response   string    `json:"response"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
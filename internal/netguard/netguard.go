@@ -0,0 +1,50 @@
+package netguard
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress means a user supplied url resolved to an address of the internal network
+var ErrForbiddenAddress = errors.New("url resolves to a private address")
+
+// IsPublic reports whether the ip is routable on the internet
+func IsPublic(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast())
+}
+
+// NewHTTPClient returns a client for user supplied urls. It refuses to connect to loopback,
+// private and link-local addresses after DNS resolution and doesn't follow redirects,
+// so users can't point it at the internal network.
+func NewHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !IsPublic(ip) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: transport,
+		// a redirect could lead anywhere, the receiver has to answer itself
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
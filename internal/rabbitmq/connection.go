@@ -0,0 +1,58 @@
+package rabbitmq
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Dialer opens connections to the broker. It is an interface so the connection can be faked.
+type Dialer interface {
+	Dial(url string) (Connection, error)
+}
+
+type Connection interface {
+	Channel() (Channel, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	Close() error
+}
+
+// Channel is the part of *amqp.Channel the service uses
+type Channel interface {
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Get(queue string, autoAck bool) (amqp.Delivery, bool, error)
+	QueuePurge(name string, noWait bool) (int, error)
+	Close() error
+}
+
+type amqpDialer struct{}
+
+// AMQPDialer dials the broker with amqp091
+func AMQPDialer() Dialer {
+	return amqpDialer{}
+}
+
+func (amqpDialer) Dial(url string) (Connection, error) {
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return amqpConnection{conn}, nil
+}
+
+type amqpConnection struct {
+	*amqp.Connection
+}
+
+func (c amqpConnection) Channel() (Channel, error) {
+	ch, err := c.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	return ch, nil
+}
@@ -0,0 +1,268 @@
+package rabbitmq
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// declareQueue declares the queue with the arguments every producer declares it with.
+// The queues this service consumes also get their dead letter queue declared. The ones it owns
+// dead-letter into it through their arguments, the shared ones only once a policy on the queue
+// says so (see the README): RabbitMQ refuses to redeclare a queue with other arguments,
+// so the shared ones keep the plain declaration of the other services.
+func declareQueue(ch Channel, queue string) (amqp.Queue, error) {
+	if slices.Contains(ConsumedQueues(), queue) {
+		if _, err := declareDeadLetterQueue(ch, queue); err != nil {
+			return amqp.Queue{}, err
+		}
+	}
+
+	var args amqp.Table
+	if slices.Contains(OwnedQueues(), queue) {
+		args = amqp.Table{
+			"x-dead-letter-exchange": "",
+			"x-dead-letter-routing-key": DeadLetterQueue(queue),
+		}
+	}
+
+	return ch.QueueDeclare(
+		queue,
+		true,
+		false,
+		false,
+		false,
+		args,
+	)
+}
+
+func declareDeadLetterQueue(ch Channel, queue string) (amqp.Queue, error) {
+	return ch.QueueDeclare(
+		DeadLetterQueue(queue),
+		true,
+		false,
+		false,
+		false,
+		nil,
+	)
+}
+
+// DeadLetterID returns the id DeadLetter gave the message. The broker dead-letters messages without one,
+// so theirs is a hash of the message and of where it was first dead-lettered, which don't change while it waits.
+func DeadLetterID(msg amqp.Delivery) string {
+	if id, ok := msg.Headers[DEAD_LETTER_ID_HEADER].(string); ok && id != "" {
+		return id
+	}
+
+	hash := sha256.New()
+	for _, header := range []string{"x-first-death-queue", "x-first-death-reason", "x-first-death-exchange"} {
+		value, _ := msg.Headers[header].(string)
+		fmt.Fprintf(hash, "%s\x00", value)
+	}
+	fmt.Fprintf(hash, "%s\x00%d\x00", msg.MessageId, msg.Timestamp.Unix())
+	hash.Write(msg.Body)
+
+	return hex.EncodeToString(hash.Sum(nil)[:16])
+}
+
+// CountDeadLetters returns how many messages the queue's dead letter queue holds
+func (mq *MQConn) CountDeadLetters(queue string) (int, error) {
+	ch, err := mq.Channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	q, err := declareDeadLetterQueue(ch, queue)
+	if err != nil {
+		return 0, err
+	}
+
+	return q.Messages, nil
+}
+
+// scanDeadLetters gets every message that was in the queue's dead letter queue when the scan started
+// and passes it to fn, stopping once fn returns false. The messages fn doesn't ack or nack
+// return to the dead letter queue when the channel closes, keeping their position.
+func (mq *MQConn) scanDeadLetters(queue string, fn func(ch Channel, msg amqp.Delivery) (bool, error)) error {
+	ch, err := mq.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	q, err := declareDeadLetterQueue(ch, queue)
+	if err != nil {
+		return err
+	}
+
+	// bounded by the count, so messages dead-lettered again while replaying aren't seen twice
+	for range q.Messages {
+		msg, ok, err := ch.Get(q.Name, false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+
+		more, err := fn(ch, msg)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// PeekDeadLetters returns up to limit messages from the head of the queue's dead letter queue, leaving them there
+func (mq *MQConn) PeekDeadLetters(queue string, limit int) ([]amqp.Delivery, error) {
+	var msgs []amqp.Delivery
+	if limit <= 0 {
+		return msgs, nil
+	}
+
+	err := mq.scanDeadLetters(queue, func(ch Channel, msg amqp.Delivery) (bool, error) {
+		msgs = append(msgs, msg)
+		return len(msgs) < limit, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
+
+// FindDeadLetter returns the message with the id from the queue's dead letter queue, leaving it there
+func (mq *MQConn) FindDeadLetter(queue string, id string) (*amqp.Delivery, error) {
+	if id == "" {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	var found *amqp.Delivery
+	err := mq.scanDeadLetters(queue, func(ch Channel, msg amqp.Delivery) (bool, error) {
+		if DeadLetterID(msg) == id {
+			found = &msg
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, ErrDeadLetterNotFound
+	}
+
+	return found, nil
+}
+
+// ReplayDeadLetters publishes every message from the queue's dead letter queue back to the queue
+// as a first attempt, and returns how many it replayed
+func (mq *MQConn) ReplayDeadLetters(queue string) (int, error) {
+	replayed := 0
+	err := mq.scanDeadLetters(queue, func(ch Channel, msg amqp.Delivery) (bool, error) {
+		if err := replayDeadLetter(ch, queue, msg); err != nil {
+			return false, err
+		}
+
+		replayed++
+		return true, nil
+	})
+
+	return replayed, err
+}
+
+// ReplayDeadLetter publishes the message with the id from the queue's dead letter queue back to the queue as a first attempt
+func (mq *MQConn) ReplayDeadLetter(queue string, id string) error {
+	if id == "" {
+		return ErrDeadLetterNotFound
+	}
+
+	replayed := false
+	err := mq.scanDeadLetters(queue, func(ch Channel, msg amqp.Delivery) (bool, error) {
+		if DeadLetterID(msg) != id {
+			return true, nil
+		}
+		if err := replayDeadLetter(ch, queue, msg); err != nil {
+			return false, err
+		}
+
+		replayed = true
+		return false, nil
+	})
+	if err == nil && !replayed {
+		err = ErrDeadLetterNotFound
+	}
+
+	return err
+}
+
+// replayDeadLetter publishes the dead letter to the queue without the headers of its previous attempts and acks it
+func replayDeadLetter(ch Channel, queue string, msg amqp.Delivery) error {
+	headers := copyHeaders(msg.Headers)
+	for _, header := range []string{ATTEMPT_HEADER, ERROR_HEADER, ORIGINAL_QUEUE_HEADER, DEAD_LETTER_ID_HEADER, "x-death"} {
+		delete(headers, header)
+	}
+
+	if err := ch.Publish("", queue, false, false, amqp.Publishing{
+		Headers: headers,
+		DeliveryMode: amqp.Persistent,
+		ContentType: msg.ContentType,
+		MessageId: msg.MessageId,
+		Body: msg.Body,
+	}); err != nil {
+		return err
+	}
+
+	return msg.Ack(false)
+}
+
+// PurgeDeadLetters drops every message from the queue's dead letter queue and returns how many it dropped
+func (mq *MQConn) PurgeDeadLetters(queue string) (int, error) {
+	ch, err := mq.Channel()
+	if err != nil {
+		return 0, err
+	}
+	defer ch.Close()
+
+	if _, err := declareDeadLetterQueue(ch, queue); err != nil {
+		return 0, err
+	}
+
+	return ch.QueuePurge(DeadLetterQueue(queue), false)
+}
+
+// PurgeDeadLetter drops the message with the id from the queue's dead letter queue
+func (mq *MQConn) PurgeDeadLetter(queue string, id string) error {
+	if id == "" {
+		return ErrDeadLetterNotFound
+	}
+
+	purged := false
+	err := mq.scanDeadLetters(queue, func(ch Channel, msg amqp.Delivery) (bool, error) {
+		if DeadLetterID(msg) != id {
+			return true, nil
+		}
+		if err := msg.Ack(false); err != nil {
+			return false, err
+		}
+
+		purged = true
+		return false, nil
+	})
+	if err == nil && !purged {
+		err = ErrDeadLetterNotFound
+	}
+
+	return err
+}
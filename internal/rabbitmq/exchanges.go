@@ -3,4 +3,5 @@ package rabbitmq
 const (
 	USERS_UPDATE_EXCHANGE = "users.update"
 	USERS_CREATED_EXCHANGE = "users.created"
+	PRESENCE_EXCHANGE = "notifications.presence"
 )
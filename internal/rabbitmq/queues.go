@@ -3,9 +3,55 @@ package rabbitmq
 const (
 	REGISTRATION_CODE_MAIL_QUEUE = "notifications.registration_code"
 	SIGNIN_CODE_MAIL_QUEUE = "notifications.signin_code"
+	SIGNIN_CODE_SMS_QUEUE = "notifications.signin_code_sms"
+	DIGEST_MAIL_QUEUE = "notifications.digest"
+	PASSWORD_RESET_MAIL_QUEUE = "notifications.password_reset"
+	EMAIL_CHANGE_MAIL_QUEUE = "notifications.email_change"
+	NEW_DEVICE_SIGNIN_MAIL_QUEUE = "notifications.new_device_signin"
+	MAIL_EVENTS_QUEUE = "notifications.mail_events"
+	MAIL_RATE_LIMITED_QUEUE = "notifications.mail_rate_limited"
+	SMS_RATE_LIMITED_QUEUE = "notifications.sms_rate_limited"
+	WEBHOOK_DELIVERY_QUEUE = "notifications.webhook_deliveries"
+	PUSH_DELIVERY_QUEUE = "notifications.push_deliveries"
+	USERS_CREATED_QUEUE = "notifications.users_created"
+	USERS_UPDATE_QUEUE = "notifications.users_update"
 	NEW_POST_QUEUE = "new-post"
 	NEW_POST_NOTIFICATION_QUEUE = "new-post-notification"
 	FOLLOWS_QUEUE = "follows"
 	FOLLOWERS_NEW_POST_NOTIFICATIONS_ENABLED_UPDATES_QUEUE = "followers-new-post-notifications-enabled-updates"
 	POST_VALIDATION_STATUS_UPDATES_QUEUE = "post-validation-status-updates"
 )
+
+// ConsumedQueues returns the queues this service consumes. Each of them dead-letters
+// to its own dead letter queue, named by DeadLetterQueue.
+func ConsumedQueues() []string {
+	return []string{
+		REGISTRATION_CODE_MAIL_QUEUE,
+		SIGNIN_CODE_MAIL_QUEUE,
+		SIGNIN_CODE_SMS_QUEUE,
+		DIGEST_MAIL_QUEUE,
+		PASSWORD_RESET_MAIL_QUEUE,
+		EMAIL_CHANGE_MAIL_QUEUE,
+		NEW_DEVICE_SIGNIN_MAIL_QUEUE,
+		MAIL_EVENTS_QUEUE,
+		WEBHOOK_DELIVERY_QUEUE,
+		PUSH_DELIVERY_QUEUE,
+		USERS_CREATED_QUEUE,
+		USERS_UPDATE_QUEUE,
+		NEW_POST_QUEUE,
+		FOLLOWS_QUEUE,
+		FOLLOWERS_NEW_POST_NOTIFICATIONS_ENABLED_UPDATES_QUEUE,
+		POST_VALIDATION_STATUS_UPDATES_QUEUE,
+	}
+}
+
+// OwnedQueues returns the consumed queues no other service declares. They are declared with
+// their dead letter queue as dead letter exchange, the others need a policy on the broker for that.
+func OwnedQueues() []string {
+	return []string{
+		WEBHOOK_DELIVERY_QUEUE,
+		PUSH_DELIVERY_QUEUE,
+		USERS_CREATED_QUEUE,
+		USERS_UPDATE_QUEUE,
+	}
+}
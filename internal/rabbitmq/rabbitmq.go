@@ -1,26 +1,283 @@
 package rabbitmq
 
-import amqp "github.com/rabbitmq/amqp091-go"
+import (
+	"errors"
+	"sync"
+	"time"
 
+	"github.com/BloggingApp/notification-service/internal/config"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+const (
+	DEFAULT_RECONNECT_INITIAL_DELAY = time.Second
+	DEFAULT_RECONNECT_MAX_DELAY = time.Second * 30
+	DEFAULT_PUBLISH_TIMEOUT = time.Second * 10
+)
+
+var (
+	ErrNotConnected = errors.New("rabbitmq connection is down")
+	ErrClosed = errors.New("rabbitmq connection is closed")
+)
+
+// MQConn keeps a connection to the broker, dialing again with backoff whenever it drops.
+// Publishing waits for the connection to come back, and the deliveries channels returned by
+// the Consume methods survive reconnects: their queues are declared and consumed again.
 type MQConn struct {
-	conn *amqp.Connection
+	logger *zap.Logger
+	dialer Dialer
+	url    string
+	cfg    config.RabbitMQConfig
+
+	mu        sync.Mutex
+	conn      Connection    // nil while reconnecting
+	connected chan struct{} // closed once conn is set, replaced when the connection drops
+	closing   chan struct{}
+	closed    bool
+
+	after func(d time.Duration) <-chan time.Time // waits out the backoff, replaced in tests
+}
+
+func New(logger *zap.Logger, url string, cfg config.RabbitMQConfig) (*MQConn, error) {
+	return NewWithDialer(logger, AMQPDialer(), url, cfg)
 }
 
-func New(url string) (*MQConn, error) {
-	conn, err := amqp.Dial(url)
+// NewWithDialer connects with the dialer, failing if the broker can't be reached right away
+func NewWithDialer(logger *zap.Logger, dialer Dialer, url string, cfg config.RabbitMQConfig) (*MQConn, error) {
+	if cfg.ReconnectInitialDelay <= 0 {
+		cfg.ReconnectInitialDelay = DEFAULT_RECONNECT_INITIAL_DELAY
+	}
+	if cfg.ReconnectMaxDelay <= 0 {
+		cfg.ReconnectMaxDelay = DEFAULT_RECONNECT_MAX_DELAY
+	}
+	if cfg.PublishTimeout <= 0 {
+		cfg.PublishTimeout = DEFAULT_PUBLISH_TIMEOUT
+	}
+
+	conn, err := dialer.Dial(url)
 	if err != nil {
 		return nil, err
 	}
 
-	return &MQConn{conn: conn}, nil
+	mq := &MQConn{
+		logger: logger,
+		dialer: dialer,
+		url: url,
+		cfg: cfg,
+		connected: make(chan struct{}),
+		closing: make(chan struct{}),
+		after: time.After,
+	}
+	mq.setConn(conn)
+
+	return mq, nil
+}
+
+func (mq *MQConn) setConn(conn Connection) {
+	mq.mu.Lock()
+	mq.conn = conn
+	close(mq.connected)
+	mq.mu.Unlock()
+
+	go mq.watch(conn)
+}
+
+// watch reconnects once the connection drops
+func (mq *MQConn) watch(conn Connection) {
+	closeErr := <-conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	mq.mu.Lock()
+	if mq.closed {
+		mq.mu.Unlock()
+		return
+	}
+	mq.mu.Unlock()
+	mq.dropConn(conn)
+
+	if closeErr != nil {
+		mq.logger.Sugar().Warnf("Lost connection to rabbitmq: %s", closeErr.Error())
+	} else {
+		mq.logger.Sugar().Warn("Lost connection to rabbitmq")
+	}
+
+	delay := mq.cfg.ReconnectInitialDelay
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-mq.closing:
+			return
+		case <-mq.after(delay):
+		}
+
+		conn, err := mq.dialer.Dial(mq.url)
+		if err != nil {
+			mq.logger.Sugar().Errorf("Failed to reconnect to rabbitmq (attempt %d): %s", attempt, err.Error())
+			delay = min(delay*2, mq.cfg.ReconnectMaxDelay)
+			continue
+		}
+
+		mq.mu.Lock()
+		if mq.closed {
+			mq.mu.Unlock()
+			conn.Close()
+			return
+		}
+		mq.mu.Unlock()
+
+		mq.logger.Sugar().Infof("Reconnected to rabbitmq after %d attempt(s)", attempt)
+		mq.setConn(conn)
+		return
+	}
+}
+
+// dropConn stops handing out conn, so callers wait for the reconnect instead
+func (mq *MQConn) dropConn(conn Connection) {
+	mq.mu.Lock()
+	defer mq.mu.Unlock()
+
+	if mq.conn == conn {
+		mq.conn = nil
+		mq.connected = make(chan struct{})
+	}
+}
+
+// waitConn returns the connection, waiting until the deadline for it to come back when it's down.
+// A zero deadline waits until the connection is back or closed.
+func (mq *MQConn) waitConn(deadline time.Time) (Connection, error) {
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	for {
+		mq.mu.Lock()
+		conn, connected, closed := mq.conn, mq.connected, mq.closed
+		mq.mu.Unlock()
+
+		if closed {
+			return nil, ErrClosed
+		}
+		if conn != nil {
+			return conn, nil
+		}
+
+		select {
+		case <-connected:
+		case <-mq.closing:
+		case <-expired:
+			return nil, ErrNotConnected
+		}
+	}
 }
 
 func (mq *MQConn) Close() error {
-	return mq.conn.Close()
+	mq.mu.Lock()
+	if mq.closed {
+		mq.mu.Unlock()
+		return nil
+	}
+	mq.closed = true
+	close(mq.closing)
+	conn := mq.conn
+	mq.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+// Channel opens a channel, waiting up to PublishTimeout for the connection if it's being reestablished
+func (mq *MQConn) Channel() (Channel, error) {
+	deadline := time.Now().Add(mq.cfg.PublishTimeout)
+	for {
+		conn, err := mq.waitConn(deadline)
+		if err != nil {
+			return nil, err
+		}
+
+		ch, err := conn.Channel()
+		if err != amqp.ErrClosed {
+			return ch, err
+		}
+
+		// the connection is gone, but watch hasn't noticed yet
+		mq.dropConn(conn)
+	}
 }
 
-func (mq *MQConn) Channel() (*amqp.Channel, error) {
-	return mq.conn.Channel()
+// subscribe starts consuming with setup and keeps doing so on a new channel whenever the channel
+// or the connection is lost. The returned channel is closed only when mq is closed.
+func (mq *MQConn) subscribe(setup func(ch Channel) (<-chan amqp.Delivery, error)) (<-chan amqp.Delivery, error) {
+	ch, err := mq.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := setup(ch)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	out := make(chan amqp.Delivery)
+	go func() {
+		defer close(out)
+
+		for {
+			for msg := range msgs {
+				select {
+				case out <- msg:
+				case <-mq.closing:
+					return
+				}
+			}
+
+			// the deliveries stop when the channel or its connection is gone,
+			// or when the broker cancels the consumer
+			ch.Close()
+			ch, msgs = mq.resubscribe(setup)
+			if ch == nil {
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// resubscribe retries setup on a new channel with backoff until it succeeds, returning a nil channel once mq is closed
+func (mq *MQConn) resubscribe(setup func(ch Channel) (<-chan amqp.Delivery, error)) (Channel, <-chan amqp.Delivery) {
+	delay := mq.cfg.ReconnectInitialDelay
+	for {
+		conn, err := mq.waitConn(time.Time{})
+		if err != nil {
+			return nil, nil
+		}
+
+		ch, err := conn.Channel()
+		if err == amqp.ErrClosed {
+			mq.dropConn(conn)
+		}
+		if err == nil {
+			msgs, setupErr := setup(ch)
+			if setupErr == nil {
+				return ch, msgs
+			}
+			ch.Close()
+			err = setupErr
+		}
+		mq.logger.Sugar().Errorf("Failed to resume consuming from rabbitmq: %s", err.Error())
+
+		select {
+		case <-mq.closing:
+			return nil, nil
+		case <-mq.after(delay):
+		}
+		delay = min(delay*2, mq.cfg.ReconnectMaxDelay)
+	}
 }
 
 func (mq *MQConn) PublishToQueue(queue string, body []byte) error {
@@ -30,14 +287,7 @@ func (mq *MQConn) PublishToQueue(queue string, body []byte) error {
 	}
 	defer ch.Close()
 
-	q, err := ch.QueueDeclare(
-		queue,
-		true,
-		false,
-		false,
-		false,
-		nil,
-	)
+	q, err := declareQueue(ch, queue)
 	if err != nil {
 		return err
 	}
@@ -75,64 +325,138 @@ func (mq *MQConn) PublishExchange(exchange string, body []byte) error {
 	)
 }
 
-func (mq *MQConn) Consume(queue string) (<-chan amqp.Delivery, error) {
+// DeclareFanoutExchange declares a durable exchange this service publishes to
+func (mq *MQConn) DeclareFanoutExchange(exchange string) error {
 	ch, err := mq.Channel()
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer ch.Close()
 
-	q, err := ch.QueueDeclare(
-		queue,
+	return ch.ExchangeDeclare(
+		exchange,
+		amqp.ExchangeFanout,
 		true,
 		false,
 		false,
 		false,
 		nil,
 	)
+}
+
+func (mq *MQConn) publish(exchange string, key string, msg amqp.Publishing) error {
+	ch, err := mq.Channel()
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer ch.Close()
 
-	return ch.Consume(
-		q.Name,
-		"",
-		false,
-		false,
-		false,
-		false,
-		nil,
-	)
+	return ch.Publish(exchange, key, false, false, msg)
+}
+
+func (mq *MQConn) Consume(queue string) (<-chan amqp.Delivery, error) {
+	return mq.subscribe(func(ch Channel) (<-chan amqp.Delivery, error) {
+		q, err := declareQueue(ch, queue)
+		if err != nil {
+			return nil, err
+		}
+
+		return ch.Consume(
+			q.Name,
+			"",
+			false,
+			false,
+			false,
+			false,
+			nil,
+		)
+	})
+}
+
+// ConsumeWithPrefetch is like Consume, but limits the unacked deliveries to prefetch,
+// so several workers can share the deliveries
+func (mq *MQConn) ConsumeWithPrefetch(queue string, prefetch int) (<-chan amqp.Delivery, error) {
+	return mq.subscribe(func(ch Channel) (<-chan amqp.Delivery, error) {
+		if err := ch.Qos(prefetch, 0, false); err != nil {
+			return nil, err
+		}
+
+		q, err := declareQueue(ch, queue)
+		if err != nil {
+			return nil, err
+		}
+
+		return ch.Consume(
+			q.Name,
+			"",
+			false,
+			false,
+			false,
+			false,
+			nil,
+		)
+	})
 }
 
+// ConsumeExchange consumes from a new exclusive queue bound to the exchange.
+// After a reconnect it's another queue, so messages published meanwhile are missed.
 func (mq *MQConn) ConsumeExchange(exchange string) (<-chan amqp.Delivery, error) {
-	ch, err := mq.Channel()
-	if err != nil {
-		return nil, err
-	}
+	return mq.subscribe(func(ch Channel) (<-chan amqp.Delivery, error) {
+		q, err := ch.QueueDeclare(
+			"",
+			false,
+			false,
+			true,
+			false,
+			nil,
+		)
+		if err != nil {
+			return nil, err
+		}
 
-	q, err := ch.QueueDeclare(
-		"",
-		false,
-		false,
-		true,
-		false,
-		nil,
-	)
-	if err != nil {
-		return nil, err
-	}
+		if err := ch.QueueBind(
+			q.Name,
+			"",
+			exchange,
+			false,
+			nil,
+		); err != nil {
+			return nil, err
+		}
 
-	if err := ch.QueueBind(
-		q.Name,
-		"",
-		exchange,
-		false,
-		nil,
-	); err != nil {
-		return nil, err
-	}
+		return ch.Consume(
+			q.Name, "", true, false, false, false, nil,
+		)
+	})
+}
 
-	return ch.Consume(
-		q.Name, "", true, false, false, false, nil,
-	)
+// ConsumeExchangeQueue consumes from the durable queue bound to the exchange, so messages
+// published while the service is down or reconnecting wait in the queue
+func (mq *MQConn) ConsumeExchangeQueue(exchange string, queue string) (<-chan amqp.Delivery, error) {
+	return mq.subscribe(func(ch Channel) (<-chan amqp.Delivery, error) {
+		q, err := declareQueue(ch, queue)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := ch.QueueBind(
+			q.Name,
+			"",
+			exchange,
+			false,
+			nil,
+		); err != nil {
+			return nil, err
+		}
+
+		return ch.Consume(
+			q.Name,
+			"",
+			false,
+			false,
+			false,
+			false,
+			nil,
+		)
+	})
 }
@@ -0,0 +1,732 @@
+package rabbitmq
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/config"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// fakeChannel records what is published and hands out deliveries pushed with deliver
+type fakeChannel struct {
+	mu         sync.Mutex
+	closed     bool
+	consumed   []string
+	declared   map[string]amqp.Table
+	deliveries chan amqp.Delivery
+	published  []fakePublishing
+}
+
+type fakePublishing struct {
+	exchange string
+	key      string
+	msg      amqp.Publishing
+}
+
+func (c *fakeChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	return nil
+}
+
+func (c *fakeChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	return nil
+}
+
+func (c *fakeChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.declared == nil {
+		c.declared = make(map[string]amqp.Table)
+	}
+	c.declared[name] = args
+	return amqp.Queue{Name: name}, nil
+}
+
+func (c *fakeChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	return nil
+}
+
+func (c *fakeChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, amqp.ErrClosed
+	}
+	c.consumed = append(c.consumed, queue)
+	c.deliveries = make(chan amqp.Delivery, 10)
+	return c.deliveries, nil
+}
+
+func (c *fakeChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return amqp.ErrClosed
+	}
+	c.published = append(c.published, fakePublishing{exchange: exchange, key: key, msg: msg})
+	return nil
+}
+
+func (c *fakeChannel) Get(queue string, autoAck bool) (amqp.Delivery, bool, error) {
+	return amqp.Delivery{}, false, nil
+}
+
+func (c *fakeChannel) QueuePurge(name string, noWait bool) (int, error) {
+	return 0, nil
+}
+
+func (c *fakeChannel) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		if c.deliveries != nil {
+			close(c.deliveries)
+		}
+	}
+	return nil
+}
+
+// deliver pushes a delivery to the channel's consumer, failing if nothing consumes
+func (c *fakeChannel) deliver(t *testing.T, body string) {
+	t.Helper()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed || c.deliveries == nil {
+		t.Fatal("channel has no consumer")
+	}
+	c.deliveries <- amqp.Delivery{Body: []byte(body)}
+}
+
+func (c *fakeChannel) publishings() []fakePublishing {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.published)
+}
+
+// fakeConn is a broker connection that tests can kill
+type fakeConn struct {
+	mu       sync.Mutex
+	closed   bool
+	notify   []chan *amqp.Error
+	channels []*fakeChannel
+}
+
+func (c *fakeConn) Channel() (Channel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, amqp.ErrClosed
+	}
+	ch := &fakeChannel{}
+	c.channels = append(c.channels, ch)
+	return ch, nil
+}
+
+func (c *fakeConn) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		close(receiver)
+	} else {
+		c.notify = append(c.notify, receiver)
+	}
+	return receiver
+}
+
+func (c *fakeConn) Close() error {
+	c.kill(nil)
+	return nil
+}
+
+// kill drops the connection like the broker going away: the channels stop and the listeners are told
+func (c *fakeConn) kill(err *amqp.Error) {
+	c.drop()
+	c.tell(err)
+}
+
+// drop loses the connection without telling the listeners yet, as happens before amqp notices
+func (c *fakeConn) drop() {
+	c.mu.Lock()
+	c.closed = true
+	channels := slices.Clone(c.channels)
+	c.mu.Unlock()
+
+	for _, ch := range channels {
+		ch.Close()
+	}
+}
+
+func (c *fakeConn) tell(err *amqp.Error) {
+	c.mu.Lock()
+	notify := c.notify
+	c.notify = nil
+	c.mu.Unlock()
+
+	for _, receiver := range notify {
+		if err != nil {
+			receiver <- err
+		}
+		close(receiver)
+	}
+}
+
+func (c *fakeConn) isWatched() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.notify) > 0
+}
+
+func (c *fakeConn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// publishings returns what was published on all the connection's channels
+func (c *fakeConn) publishings() []fakePublishing {
+	c.mu.Lock()
+	channels := slices.Clone(c.channels)
+	c.mu.Unlock()
+
+	var publishings []fakePublishing
+	for _, ch := range channels {
+		publishings = append(publishings, ch.publishings()...)
+	}
+	return publishings
+}
+
+// consumingChannel returns the channel consuming from the queue, if any
+func (c *fakeConn) consumingChannel(queue string) *fakeChannel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, ch := range c.channels {
+		ch.mu.Lock()
+		consuming := !ch.closed && slices.Contains(ch.consumed, queue)
+		ch.mu.Unlock()
+		if consuming {
+			return ch
+		}
+	}
+	return nil
+}
+
+var errBrokerDown = errors.New("connection refused")
+
+// fakeDialer fails while the broker is down and otherwise hands out new fakeConns.
+// A non-nil gate holds every dial until it's closed.
+type fakeDialer struct {
+	mu    sync.Mutex
+	down  bool
+	gate  chan struct{}
+	dials int
+	conns []*fakeConn
+}
+
+func (d *fakeDialer) Dial(url string) (Connection, error) {
+	d.mu.Lock()
+	gate := d.gate
+	d.dials++
+	d.mu.Unlock()
+
+	if gate != nil {
+		<-gate
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.down {
+		return nil, errBrokerDown
+	}
+	conn := &fakeConn{}
+	d.conns = append(d.conns, conn)
+	return conn, nil
+}
+
+func (d *fakeDialer) setDown(down bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.down = down
+}
+
+func (d *fakeDialer) dialCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.dials
+}
+
+func (d *fakeDialer) lastConn() *fakeConn {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.conns[len(d.conns)-1]
+}
+
+func (d *fakeDialer) connCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.conns)
+}
+
+func newTestConn(t *testing.T, cfg config.RabbitMQConfig) (*MQConn, *fakeDialer) {
+	t.Helper()
+
+	if cfg.ReconnectInitialDelay == 0 {
+		cfg.ReconnectInitialDelay = time.Millisecond * 5
+	}
+	if cfg.ReconnectMaxDelay == 0 {
+		cfg.ReconnectMaxDelay = time.Millisecond * 20
+	}
+	if cfg.PublishTimeout == 0 {
+		cfg.PublishTimeout = time.Second * 2
+	}
+
+	dialer := &fakeDialer{}
+	mq, err := NewWithDialer(zap.NewNop(), dialer, "amqp://test", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { mq.Close() })
+
+	// a connection dropped before it's watched would be noticed right away
+	eventually(t, "the connection to be watched", dialer.lastConn().isWatched)
+
+	return mq, dialer
+}
+
+// eventually fails the test if cond doesn't hold within a second
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func receive(t *testing.T, msgs <-chan amqp.Delivery) amqp.Delivery {
+	t.Helper()
+
+	select {
+	case msg, ok := <-msgs:
+		if !ok {
+			t.Fatal("deliveries channel closed")
+		}
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for a delivery")
+	}
+	return amqp.Delivery{}
+}
+
+func TestPublishWaitsForReconnect(t *testing.T) {
+	mq, dialer := newTestConn(t, config.RabbitMQConfig{})
+	first := dialer.lastConn()
+
+	dialer.setDown(true)
+	first.kill(&amqp.Error{Code: amqp.ConnectionForced, Reason: "broker restarting"})
+	eventually(t, "a failed reconnect", func() bool { return dialer.dialCount() > 2 })
+
+	published := make(chan error, 1)
+	go func() {
+		published <- mq.PublishToQueue("q", []byte("hello"))
+	}()
+
+	select {
+	case err := <-published:
+		t.Fatalf("publish returned before the connection was back: %v", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	dialer.setDown(false)
+	select {
+	case err := <-published:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("publish didn't resume after the reconnect")
+	}
+
+	second := dialer.lastConn()
+	if second == first {
+		t.Fatal("expected a new connection")
+	}
+	publishings := second.publishings()
+	if len(publishings) != 1 || publishings[0].key != "q" || string(publishings[0].msg.Body) != "hello" {
+		t.Fatalf("expected the message published on the new connection, got %v", publishings)
+	}
+}
+
+func TestChannelRecoversFromUnnoticedDrop(t *testing.T) {
+	mq, dialer := newTestConn(t, config.RabbitMQConfig{})
+	first := dialer.lastConn()
+
+	// the connection is gone, but amqp hasn't told anyone yet
+	first.drop()
+
+	opened := make(chan error, 1)
+	go func() {
+		ch, err := mq.Channel()
+		if err == nil {
+			ch.Close()
+		}
+		opened <- err
+	}()
+
+	select {
+	case err := <-opened:
+		t.Fatalf("Channel returned on a dead connection: %v", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	first.tell(&amqp.Error{Code: amqp.ConnectionForced, Reason: "gone"})
+	select {
+	case err := <-opened:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Channel didn't resume after the reconnect")
+	}
+	if dialer.lastConn() == first {
+		t.Fatal("expected a new connection")
+	}
+}
+
+func TestChannelTimesOutWhileDisconnected(t *testing.T) {
+	mq, dialer := newTestConn(t, config.RabbitMQConfig{PublishTimeout: time.Millisecond * 50})
+
+	dialer.setDown(true)
+	dialer.lastConn().kill(nil)
+
+	start := time.Now()
+	if _, err := mq.Channel(); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("expected ErrNotConnected, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond * 40 {
+		t.Fatalf("gave up after %s, the publish timeout is 50ms", elapsed)
+	}
+}
+
+func TestSubscribeResumesOnNewChannel(t *testing.T) {
+	mq, dialer := newTestConn(t, config.RabbitMQConfig{})
+
+	msgs, err := mq.ConsumeWithPrefetch("q", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := dialer.lastConn()
+	first.consumingChannel("q").deliver(t, "before")
+	if msg := receive(t, msgs); string(msg.Body) != "before" {
+		t.Fatalf("expected %q, got %q", "before", msg.Body)
+	}
+
+	t.Run("after the connection drops", func(t *testing.T) {
+		first.kill(&amqp.Error{Code: amqp.ConnectionForced, Reason: "broker restarting"})
+
+		var ch *fakeChannel
+		eventually(t, "consuming on a new connection", func() bool {
+			if dialer.lastConn() == first {
+				return false
+			}
+			ch = dialer.lastConn().consumingChannel("q")
+			return ch != nil
+		})
+
+		ch.deliver(t, "after reconnect")
+		if msg := receive(t, msgs); string(msg.Body) != "after reconnect" {
+			t.Fatalf("expected %q, got %q", "after reconnect", msg.Body)
+		}
+	})
+
+	t.Run("after the channel closes", func(t *testing.T) {
+		conn := dialer.lastConn()
+		old := conn.consumingChannel("q")
+		old.Close()
+
+		var ch *fakeChannel
+		eventually(t, "consuming on a new channel", func() bool {
+			ch = conn.consumingChannel("q")
+			return ch != nil && ch != old
+		})
+
+		ch.deliver(t, "after channel loss")
+		if msg := receive(t, msgs); string(msg.Body) != "after channel loss" {
+			t.Fatalf("expected %q, got %q", "after channel loss", msg.Body)
+		}
+	})
+}
+
+func TestReconnectBackoffIsCapped(t *testing.T) {
+	mq, dialer := newTestConn(t, config.RabbitMQConfig{
+		ReconnectInitialDelay: time.Second,
+		ReconnectMaxDelay: time.Second * 4,
+	})
+
+	// the backoff is recorded instead of waited out
+	var (
+		mu     sync.Mutex
+		delays []time.Duration
+	)
+	mq.after = func(d time.Duration) <-chan time.Time {
+		mu.Lock()
+		delays = append(delays, d)
+		failed := len(delays) >= 6
+		mu.Unlock()
+		if failed {
+			dialer.setDown(false)
+		}
+
+		fire := make(chan time.Time, 1)
+		fire <- time.Now()
+		return fire
+	}
+
+	dialer.setDown(true)
+	dialer.lastConn().kill(nil)
+	eventually(t, "the reconnect", func() bool { return dialer.connCount() == 2 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	expected := []time.Duration{time.Second, time.Second * 2, time.Second * 4, time.Second * 4, time.Second * 4, time.Second * 4}
+	if !slices.Equal(delays, expected) {
+		t.Fatalf("expected delays %v, got %v", expected, delays)
+	}
+}
+
+func TestCloseDuringReconnect(t *testing.T) {
+	t.Run("while dialing fails", func(t *testing.T) {
+		mq, dialer := newTestConn(t, config.RabbitMQConfig{})
+
+		msgs, err := mq.Consume("q")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dialer.setDown(true)
+		dialer.lastConn().kill(nil)
+		eventually(t, "a failed reconnect", func() bool { return dialer.dialCount() > 2 })
+
+		if err := mq.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		select {
+		case _, ok := <-msgs:
+			if ok {
+				t.Fatal("expected no deliveries")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("deliveries channel wasn't closed")
+		}
+		if _, err := mq.Channel(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+
+		// the reconnect loop stopped
+		dials := dialer.dialCount()
+		time.Sleep(time.Millisecond * 50)
+		if dialer.dialCount() != dials {
+			t.Fatal("kept dialing after Close")
+		}
+	})
+
+	t.Run("while a dial is in flight", func(t *testing.T) {
+		mq, dialer := newTestConn(t, config.RabbitMQConfig{})
+
+		gate := make(chan struct{})
+		dialer.mu.Lock()
+		dialer.gate = gate
+		dialer.mu.Unlock()
+
+		dialer.lastConn().kill(nil)
+		eventually(t, "the reconnect dial", func() bool { return dialer.dialCount() == 2 })
+
+		if err := mq.Close(); err != nil {
+			t.Fatal(err)
+		}
+		close(gate)
+
+		eventually(t, "the late connection", func() bool { return dialer.connCount() == 2 })
+		eventually(t, "the late connection to be closed", func() bool { return dialer.lastConn().isClosed() })
+		if _, err := mq.Channel(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+	})
+}
+
+func TestConsumedQueuesKeepTheirDeclaration(t *testing.T) {
+	for _, queue := range ConsumedQueues() {
+		if slices.Contains(OwnedQueues(), queue) {
+			continue
+		}
+
+		ch := &fakeChannel{}
+		if _, err := declareQueue(ch, queue); err != nil {
+			t.Fatal(err)
+		}
+
+		// other producers declare the queue without arguments, declaring it with any would fail
+		if args, ok := ch.declared[queue]; !ok || args != nil {
+			t.Fatalf("expected %s declared without arguments, got %v", queue, args)
+		}
+		if _, ok := ch.declared[DeadLetterQueue(queue)]; !ok {
+			t.Fatalf("expected the dead letter queue of %s declared", queue)
+		}
+	}
+}
+
+func TestOwnedQueuesDeadLetterIntoTheirDeadLetterQueue(t *testing.T) {
+	for _, queue := range OwnedQueues() {
+		if !slices.Contains(ConsumedQueues(), queue) {
+			t.Fatalf("expected owned queue %s to be consumed", queue)
+		}
+
+		ch := &fakeChannel{}
+		if _, err := declareQueue(ch, queue); err != nil {
+			t.Fatal(err)
+		}
+
+		args := ch.declared[queue]
+		if args["x-dead-letter-exchange"] != "" || args["x-dead-letter-routing-key"] != DeadLetterQueue(queue) {
+			t.Fatalf("expected %s to dead-letter into %s, got %v", queue, DeadLetterQueue(queue), args)
+		}
+		if _, ok := ch.declared[DeadLetterQueue(queue)]; !ok {
+			t.Fatalf("expected the dead letter queue of %s declared", queue)
+		}
+	}
+}
+
+// fakeAcknowledger records how a delivery was settled
+type fakeAcknowledger struct {
+	mu      sync.Mutex
+	acked   bool
+	nacked  bool
+	requeue bool
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.acked = true
+	return nil
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nacked = true
+	a.requeue = requeue
+	return nil
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
+
+func (a *fakeAcknowledger) settled() (acked, nacked, requeue bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.acked, a.nacked, a.requeue
+}
+
+func TestRejectDeadLettersAndAcks(t *testing.T) {
+	mq, dialer := newTestConn(t, config.RabbitMQConfig{})
+
+	ack := &fakeAcknowledger{}
+	mq.Reject(FOLLOWS_QUEUE, amqp.Delivery{Acknowledger: ack, Body: []byte("{}")}, errors.New("bad follow"))
+
+	if acked, nacked, _ := ack.settled(); !acked || nacked {
+		t.Fatalf("expected the delivery acked, got acked %v nacked %v", acked, nacked)
+	}
+	publishings := dialer.lastConn().publishings()
+	if len(publishings) != 1 || publishings[0].key != DeadLetterQueue(FOLLOWS_QUEUE) {
+		t.Fatalf("expected one publish to %s, got %v", DeadLetterQueue(FOLLOWS_QUEUE), publishings)
+	}
+}
+
+func TestRejectRequeuesWhenDeadLetteringFails(t *testing.T) {
+	mq, dialer := newTestConn(t, config.RabbitMQConfig{PublishTimeout: time.Millisecond * 20})
+
+	// the requeue delay is recorded instead of waited out
+	var (
+		mu      sync.Mutex
+		delayed bool
+	)
+	mq.after = func(d time.Duration) <-chan time.Time {
+		if d == REQUEUE_DELAY {
+			mu.Lock()
+			delayed = true
+			mu.Unlock()
+		}
+
+		fire := make(chan time.Time, 1)
+		fire <- time.Now()
+		return fire
+	}
+
+	dialer.setDown(true)
+	dialer.lastConn().kill(nil)
+
+	ack := &fakeAcknowledger{}
+	mq.Reject(FOLLOWS_QUEUE, amqp.Delivery{Acknowledger: ack, Body: []byte("{}")}, errors.New("bad follow"))
+
+	acked, nacked, requeue := ack.settled()
+	if acked || !nacked || !requeue {
+		t.Fatalf("expected the delivery requeued, got acked %v nacked %v requeue %v", acked, nacked, requeue)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !delayed {
+		t.Fatal("expected the requeue to wait out REQUEUE_DELAY")
+	}
+}
+
+func TestDeadLetterIDOfBrokerDeadLetteredMessages(t *testing.T) {
+	msg := amqp.Delivery{
+		Headers: amqp.Table{
+			"x-first-death-queue": FOLLOWS_QUEUE,
+			"x-first-death-reason": "rejected",
+			"x-first-death-exchange": "",
+		},
+		Body: []byte(`{"follower_id":"1"}`),
+	}
+
+	id := DeadLetterID(msg)
+	if id == "" {
+		t.Fatal("expected an id for a message without one")
+	}
+	if again := DeadLetterID(msg); again != id {
+		t.Fatalf("expected the same id on every read, got %s and %s", id, again)
+	}
+
+	other := msg
+	other.Body = []byte(`{"follower_id":"2"}`)
+	if DeadLetterID(other) == id {
+		t.Fatal("expected another id for another body")
+	}
+
+	given := msg
+	given.Headers = amqp.Table{DEAD_LETTER_ID_HEADER: "given-id"}
+	if got := DeadLetterID(given); got != "given-id" {
+		t.Fatalf("expected the id DeadLetter gave, got %s", got)
+	}
+}
@@ -0,0 +1,170 @@
+package rabbitmq
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	ATTEMPT_HEADER = "x-attempt"
+	ERROR_HEADER = "x-error"
+	ORIGINAL_QUEUE_HEADER = "x-original-queue"
+	DEAD_LETTER_ID_HEADER = "x-dead-letter-id"
+
+	// how long a delivery that can be neither retried nor dead-lettered waits before it's requeued
+	REQUEUE_DELAY = time.Second * 5
+)
+
+// RetryPolicy retries a message after InitialDelay, doubling the delay on every next attempt.
+// MaxAttempts counts the first delivery too.
+type RetryPolicy struct {
+	MaxAttempts  int
+	InitialDelay time.Duration
+}
+
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	return p.InitialDelay * time.Duration(1<<(attempt-1))
+}
+
+// RetryQueue names the delay queue by its delay, since a declared queue's TTL can't change:
+// changing the retry policy declares new delay queues instead of conflicting with the old ones
+func RetryQueue(queue string, delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%dms", queue, delay.Milliseconds())
+}
+
+func DeadLetterQueue(queue string) string {
+	return queue + ".dead"
+}
+
+// DeclareRetryQueues declares a delay queue for every retry of the queue and its dead letter queue.
+// Messages wait in a delay queue until their TTL expires and then get routed back to the queue.
+// The first delay queue is always declared, Postpone uses it.
+func (mq *MQConn) DeclareRetryQueues(queue string, policy RetryPolicy) error {
+	ch, err := mq.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	for attempt := 1; attempt < max(policy.MaxAttempts, 2); attempt++ {
+		if _, err := ch.QueueDeclare(
+			RetryQueue(queue, policy.Delay(attempt)),
+			true,
+			false,
+			false,
+			false,
+			amqp.Table{
+				"x-message-ttl": policy.Delay(attempt).Milliseconds(),
+				"x-dead-letter-exchange": "",
+				"x-dead-letter-routing-key": queue,
+			},
+		); err != nil {
+			return err
+		}
+	}
+
+	_, err = declareDeadLetterQueue(ch, queue)
+	return err
+}
+
+// Attempt returns which attempt of processing the delivery this is, starting from 1
+func Attempt(msg amqp.Delivery) int {
+	switch attempt := msg.Headers[ATTEMPT_HEADER].(type) {
+	case int32:
+		return int(attempt)
+	case int64:
+		return int(attempt)
+	case int:
+		return attempt
+	}
+	return 1
+}
+
+// Retry schedules the next attempt of the delivery, or dead-letters it if the attempts are exhausted.
+// The caller still has to ack the original delivery.
+func (mq *MQConn) Retry(queue string, msg amqp.Delivery, policy RetryPolicy, cause error) (deadLettered bool, err error) {
+	attempt := Attempt(msg)
+	if attempt >= policy.MaxAttempts {
+		return true, mq.DeadLetter(queue, msg, cause)
+	}
+
+	headers := copyHeaders(msg.Headers)
+	headers[ATTEMPT_HEADER] = int32(attempt + 1)
+	headers[ERROR_HEADER] = cause.Error()
+
+	return false, mq.publish("", RetryQueue(queue, policy.Delay(attempt)), amqp.Publishing{
+		Headers: headers,
+		DeliveryMode: amqp.Persistent,
+		ContentType: msg.ContentType,
+		MessageId: msg.MessageId,
+		Body: msg.Body,
+	})
+}
+
+// Postpone sends the delivery through the queue's first delay queue without counting an attempt,
+// for failures that aren't the message's fault, like throttling. The caller still has to ack the original delivery.
+func (mq *MQConn) Postpone(queue string, msg amqp.Delivery, policy RetryPolicy) error {
+	return mq.publish("", RetryQueue(queue, policy.Delay(1)), amqp.Publishing{
+		Headers: copyHeaders(msg.Headers),
+		DeliveryMode: amqp.Persistent,
+		ContentType: msg.ContentType,
+		MessageId: msg.MessageId,
+		Body: msg.Body,
+	})
+}
+
+// DeadLetter moves the delivery to the queue's dead letter queue with the cause in its headers.
+// The caller still has to ack the original delivery.
+func (mq *MQConn) DeadLetter(queue string, msg amqp.Delivery, cause error) error {
+	headers := copyHeaders(msg.Headers)
+	headers[ATTEMPT_HEADER] = int32(Attempt(msg))
+	headers[ORIGINAL_QUEUE_HEADER] = queue
+	headers[DEAD_LETTER_ID_HEADER] = uuid.NewString()
+	if cause != nil {
+		headers[ERROR_HEADER] = cause.Error()
+	}
+
+	return mq.publish("", DeadLetterQueue(queue), amqp.Publishing{
+		Headers: headers,
+		DeliveryMode: amqp.Persistent,
+		ContentType: msg.ContentType,
+		MessageId: msg.MessageId,
+		Timestamp: time.Now(),
+		Body: msg.Body,
+	})
+}
+
+// Reject dead-letters the delivery with the cause and acks it. Should that fail, the delivery is
+// requeued after REQUEUE_DELAY instead of being dropped, so it's retried once the broker takes publishes again.
+func (mq *MQConn) Reject(queue string, msg amqp.Delivery, cause error) {
+	if err := mq.DeadLetter(queue, msg, cause); err != nil {
+		mq.logger.Sugar().Errorf("Failed to dead-letter message from queue(%s), requeueing it: %s", queue, err.Error())
+		mq.requeue(msg)
+		return
+	}
+
+	msg.Ack(false)
+}
+
+// requeue returns the delivery to its queue after REQUEUE_DELAY, so a message that can't be
+// rescheduled isn't redelivered in a tight loop. It blocks the caller meanwhile, which also
+// holds back the next deliveries of the consumer.
+func (mq *MQConn) requeue(msg amqp.Delivery) {
+	select {
+	case <-mq.closing:
+	case <-mq.after(REQUEUE_DELAY):
+	}
+
+	msg.Nack(false, true)
+}
+
+func copyHeaders(headers amqp.Table) amqp.Table {
+	copied := make(amqp.Table, len(headers)+3)
+	for k, v := range headers {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,116 @@
+package rabbitmq
+
+import (
+	"errors"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// permanentError is a failure that no retry can fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks the failure as one no retry can fix, workers dead-letter it right away
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var pErr *permanentError
+	return errors.As(err, &pErr)
+}
+
+// Worker handles the deliveries of a queue, retrying failed ones with backoff
+// and dead-lettering the ones that fail permanently or run out of attempts
+type Worker struct {
+	mq          *MQConn
+	queue       string
+	policy      RetryPolicy
+	isPermanent func(err error) bool
+}
+
+// NewWorker returns a worker for the queue. isPermanent tells which failures aren't retried,
+// nil means only the ones marked with Permanent.
+func (mq *MQConn) NewWorker(queue string, policy RetryPolicy, isPermanent func(err error) bool) *Worker {
+	if isPermanent == nil {
+		isPermanent = IsPermanent
+	}
+
+	return &Worker{
+		mq: mq,
+		queue: queue,
+		policy: policy,
+		isPermanent: isPermanent,
+	}
+}
+
+// Run declares the queue's retry queues and handles deliveries in the number of goroutines,
+// prefetching at least one delivery per goroutine. It blocks until the deliveries channel is closed.
+func (w *Worker) Run(workers int, prefetch int, handle func(msg amqp.Delivery)) {
+	if err := w.mq.DeclareRetryQueues(w.queue, w.policy); err != nil {
+		w.mq.logger.Sugar().Fatalf("Failed to declare retry queues(%s): %s", w.queue, err.Error())
+	}
+
+	workers = max(workers, 1)
+	msgs, err := w.mq.ConsumeWithPrefetch(w.queue, max(prefetch, workers))
+	if err != nil {
+		w.mq.logger.Sugar().Fatalf("Failed to start consuming(%s): %s", w.queue, err.Error())
+	}
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range msgs {
+				handle(msg)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Fail acks the delivery after scheduling its retry with backoff,
+// or after dead-lettering it when the failure is permanent or the attempts are exhausted.
+// When neither can be published the delivery is requeued after REQUEUE_DELAY.
+func (w *Worker) Fail(msg amqp.Delivery, cause error) {
+	var err error
+	deadLettered := true
+	if w.isPermanent(cause) {
+		err = w.mq.DeadLetter(w.queue, msg, cause)
+	} else {
+		deadLettered, err = w.mq.Retry(w.queue, msg, w.policy, cause)
+	}
+	if err != nil {
+		w.mq.logger.Sugar().Errorf("Failed to reschedule message from queue(%s): %s", w.queue, err.Error())
+		w.mq.requeue(msg)
+		return
+	}
+
+	if deadLettered {
+		w.mq.logger.Sugar().Errorf("Message from queue(%s) was dead-lettered after %d attempt(s): %s", w.queue, Attempt(msg), cause.Error())
+	}
+
+	msg.Ack(false)
+}
+
+// Postpone acks the delivery after sending it through a delay queue without using up an attempt
+func (w *Worker) Postpone(msg amqp.Delivery) {
+	if err := w.mq.Postpone(w.queue, msg, w.policy); err != nil {
+		w.mq.logger.Sugar().Errorf("Failed to postpone message from queue(%s): %s", w.queue, err.Error())
+		w.mq.requeue(msg)
+		return
+	}
+
+	msg.Ack(false)
+}
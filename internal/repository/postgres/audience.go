@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+)
+
+// audienceConditions builds the WHERE conditions (joined with AND, each starting with " AND ")
+// matching users in the audience. The users table must be aliased as u.
+// Placeholders are numbered after the args that are already there.
+func audienceConditions(audience *model.GlobalNotificationAudience, args []interface{}) (string, []interface{}) {
+	if audience.IsEveryone() {
+		return "", args
+	}
+
+	conditions := ""
+
+	if len(audience.UserIDs) > 0 {
+		args = append(args, audience.UserIDs)
+		conditions += fmt.Sprintf(" AND u.id = ANY($%d)", len(args))
+	}
+
+	if audience.CreatedAfter != nil {
+		args = append(args, *audience.CreatedAfter)
+		conditions += fmt.Sprintf(" AND u.created_at > $%d", len(args))
+	}
+
+	if audience.MinFollowers != nil {
+		args = append(args, *audience.MinFollowers)
+		conditions += fmt.Sprintf(" AND (SELECT COUNT(*) FROM followers f WHERE f.user_id = u.id) >= $%d", len(args))
+	}
+
+	if audience.Locale != nil {
+		args = append(args, *audience.Locale)
+		// "en" matches "en" and regional variants like "en-US", compared without LIKE so wildcards in the locale stay literal
+		conditions += fmt.Sprintf(" AND (u.locale = $%d OR split_part(u.locale, '-', 1) = $%d)", len(args), len(args))
+	}
+
+	return conditions, args
+}
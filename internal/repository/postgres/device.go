@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type deviceRepo struct {
+	db *pgxpool.Pool
+}
+
+func newDeviceRepo(db *pgxpool.Pool) Device {
+	return &deviceRepo{
+		db: db,
+	}
+}
+
+// CreateToken stores the device token. A token identifies one app install,
+// so registering it again moves it to the current user.
+func (r *deviceRepo) CreateToken(ctx context.Context, device model.DeviceToken) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(
+		ctx,
+		`
+		INSERT INTO device_tokens(user_id, platform, token, created_at, updated_at)
+		VALUES($1, $2, $3, NOW(), NOW())
+		ON CONFLICT (token) DO UPDATE
+		SET user_id = EXCLUDED.user_id, platform = EXCLUDED.platform, updated_at = NOW()
+		RETURNING id
+		`,
+		device.UserID, device.Platform, device.Token,
+	).Scan(&id)
+	return id, err
+}
+
+func (r *deviceRepo) GetUserTokens(ctx context.Context, userID uuid.UUID) ([]*model.DeviceToken, error) {
+	rows, err := r.db.Query(
+		ctx,
+		"SELECT d.id, d.user_id, d.platform, d.token, d.created_at, d.updated_at FROM device_tokens d WHERE d.user_id = $1 ORDER BY d.updated_at DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var devices []*model.DeviceToken
+	for rows.Next() {
+		var d model.DeviceToken
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Platform, &d.Token, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		devices = append(devices, &d)
+	}
+
+	return devices, rows.Err()
+}
+
+// GetUsersWithTokens returns which of the users have a device token of any of the platforms
+func (r *deviceRepo) GetUsersWithTokens(ctx context.Context, userIDs []uuid.UUID, platforms []string) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(
+		ctx,
+		"SELECT DISTINCT d.user_id FROM device_tokens d WHERE d.user_id = ANY($1) AND d.platform = ANY($2)",
+		userIDs, platforms,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users = append(users, id)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *deviceRepo) DeleteToken(ctx context.Context, userID uuid.UUID, id int64) error {
+	var deleted int64
+	return r.db.QueryRow(
+		ctx,
+		"DELETE FROM device_tokens WHERE id = $1 AND user_id = $2 RETURNING id",
+		id, userID,
+	).Scan(&deleted)
+}
+
+func (r *deviceRepo) DeleteInvalidToken(ctx context.Context, token string) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM device_tokens WHERE token = $1", token)
+	return err
+}
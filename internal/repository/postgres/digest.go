@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	DIGEST_STATUS_CLAIMED = "claimed"
+	DIGEST_STATUS_SENT = "sent"
+)
+
+type digestRepo struct {
+	db *pgxpool.Pool
+}
+
+func newDigestRepo(db *pgxpool.Pool) Digest {
+	return &digestRepo{
+		db: db,
+	}
+}
+
+// GetRecipients returns users subscribed to digests whose local time is at localHour now
+func (r *digestRepo) GetRecipients(ctx context.Context, localHour int) ([]*model.DigestRecipient, error) {
+	rows, err := r.db.Query(
+		ctx,
+		`
+		SELECT u.id, u.username, u.email, p.digest_frequency, p.timezone
+		FROM notification_preferences p
+		JOIN users u ON u.id = p.user_id
+		WHERE p.digest_frequency <> $1
+			AND u.email IS NOT NULL
+			AND EXTRACT(HOUR FROM NOW() AT TIME ZONE p.timezone) = $2
+		`,
+		model.DIGEST_FREQUENCY_OFF, localHour,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []*model.DigestRecipient
+	for rows.Next() {
+		var recipient model.DigestRecipient
+		if err := rows.Scan(&recipient.UserID, &recipient.Username, &recipient.Email, &recipient.DigestFrequency, &recipient.Timezone); err != nil {
+			return nil, err
+		}
+
+		recipients = append(recipients, &recipient)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+// Claim records that the user's digest for the period is being sent.
+// It returns false if the digest has been sent or claimed less than lease ago, by this or another replica.
+// A claim older than the lease was left by a replica that crashed before sending, so it's taken over.
+func (r *digestRepo) Claim(ctx context.Context, userID uuid.UUID, periodKey string, lease time.Duration) (bool, error) {
+	var claimedUserID uuid.UUID
+	err := r.db.QueryRow(
+		ctx,
+		`
+		INSERT INTO digest_sends(user_id, period_key, status, claimed_at)
+		VALUES($1, $2, $3, NOW())
+		ON CONFLICT (user_id, period_key) DO UPDATE SET claimed_at = NOW()
+		WHERE digest_sends.status = $3
+			AND (digest_sends.claimed_at IS NULL OR digest_sends.claimed_at < NOW() - MAKE_INTERVAL(secs => $4))
+		RETURNING user_id
+		`,
+		userID, periodKey, DIGEST_STATUS_CLAIMED, lease.Seconds(),
+	).Scan(&claimedUserID)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Release removes a claim whose digest couldn't be sent, so the next run picks it up again
+func (r *digestRepo) Release(ctx context.Context, userID uuid.UUID, periodKey string) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM digest_sends WHERE user_id = $1 AND period_key = $2 AND status = $3", userID, periodKey, DIGEST_STATUS_CLAIMED)
+	return err
+}
+
+func (r *digestRepo) MarkSent(ctx context.Context, userID uuid.UUID, periodKey string, notificationsCount int64) error {
+	_, err := r.db.Exec(
+		ctx,
+		"UPDATE digest_sends SET status = $1, notifications_count = $2, sent_at = NOW() WHERE user_id = $3 AND period_key = $4",
+		DIGEST_STATUS_SENT, notificationsCount, userID, periodKey,
+	)
+	return err
+}
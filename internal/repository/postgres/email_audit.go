@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	GET_EMAIL_AUDIT_MAX_LIMIT = 100
+	OLD_EMAIL_AUDIT_DAYS = 90
+)
+
+type emailAuditRepo struct {
+	db *pgxpool.Pool
+}
+
+func newEmailAuditRepo(db *pgxpool.Pool) EmailAudit {
+	return &emailAuditRepo{
+		db: db,
+	}
+}
+
+func (r *emailAuditRepo) Create(ctx context.Context, record model.EmailAuditRecord) error {
+	_, err := r.db.Exec(
+		ctx,
+		`
+		INSERT INTO email_audit(type, recipient, message_id, status, smtp_response, detail, attempt, created_at)
+		VALUES($1, LOWER($2), $3, $4, $5, $6, $7, NOW())
+		`,
+		record.Type, record.Recipient, record.MessageID, record.Status, record.SMTPResponse, record.Detail, record.Attempt,
+	)
+	return err
+}
+
+// Search finds the newest records first. Empty recipient and zero times are not filtered on.
+func (r *emailAuditRepo) Search(ctx context.Context, recipient string, from, to time.Time, limit, offset int) ([]*model.EmailAuditRecord, error) {
+	if limit > GET_EMAIL_AUDIT_MAX_LIMIT {
+		limit = GET_EMAIL_AUDIT_MAX_LIMIT
+	}
+
+	var fromArg, toArg *time.Time
+	if !from.IsZero() {
+		fromArg = &from
+	}
+	if !to.IsZero() {
+		toArg = &to
+	}
+
+	rows, err := r.db.Query(
+		ctx,
+		`
+		SELECT a.id, a.type, a.recipient, a.message_id, a.status, a.smtp_response, a.detail, a.attempt, a.created_at
+		FROM email_audit a
+		WHERE ($1 = '' OR a.recipient = LOWER($1))
+		AND ($2::timestamptz IS NULL OR a.created_at >= $2)
+		AND ($3::timestamptz IS NULL OR a.created_at < $3)
+		ORDER BY a.created_at DESC
+		LIMIT $4
+		OFFSET $5
+		`,
+		recipient, fromArg, toArg, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*model.EmailAuditRecord
+	for rows.Next() {
+		var a model.EmailAuditRecord
+		if err := rows.Scan(&a.ID, &a.Type, &a.Recipient, &a.MessageID, &a.Status, &a.SMTPResponse, &a.Detail, &a.Attempt, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		records = append(records, &a)
+	}
+
+	return records, rows.Err()
+}
+
+func (r *emailAuditRepo) DeleteOldRecords(ctx context.Context) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM email_audit WHERE created_at < NOW() - MAKE_INTERVAL(days => $1)", OLD_EMAIL_AUDIT_DAYS)
+	return err
+}
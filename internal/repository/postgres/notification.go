@@ -3,9 +3,11 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/BloggingApp/notification-service/internal/model"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -94,7 +96,7 @@ func (r *notificationRepo) GetUserNotifications(ctx context.Context, userID uuid
 	rows, err := r.db.Query(
 		ctx,
 		`
-		SELECT n.id, n.type, n.content, n.resource_id, n.created_at
+		SELECT n.id, n.type, n.content, n.resource_id, n.read_at, n.created_at
 		FROM notifications n
 		WHERE n.receiver_id = $1
 		ORDER BY n.created_at DESC
@@ -111,7 +113,7 @@ func (r *notificationRepo) GetUserNotifications(ctx context.Context, userID uuid
 	var notifications []*model.Notification
 	for rows.Next() {
 		var n model.Notification
-		if err := rows.Scan(&n.ID, &n.Type, &n.Content, &n.ResourceID, &n.CreatedAt); err != nil {
+		if err := rows.Scan(&n.ID, &n.Type, &n.Content, &n.ResourceID, &n.ReadAt, &n.CreatedAt); err != nil {
 			return nil, err
 		}
 		n.ReceiverID = userID
@@ -126,16 +128,159 @@ func (r *notificationRepo) GetUserNotifications(ctx context.Context, userID uuid
 	return notifications, nil
 }
 
+func (r *notificationRepo) MarkAsRead(ctx context.Context, userID uuid.UUID, notificationID int64) error {
+	var id int64
+	return r.db.QueryRow(
+		ctx,
+		"UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND receiver_id = $2 RETURNING id",
+		notificationID, userID,
+	).Scan(&id)
+}
+
+func (r *notificationRepo) CountUnread(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.QueryRow(
+		ctx,
+		"SELECT COUNT(*) FROM notifications WHERE receiver_id = $1 AND read_at IS NULL",
+		userID,
+	).Scan(&count)
+	return count, err
+}
+
+func (r *notificationRepo) GetUnreadNotificationsSince(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]*model.Notification, int64, error) {
+	var total int64
+	if err := r.db.QueryRow(
+		ctx,
+		"SELECT COUNT(*) FROM notifications WHERE receiver_id = $1 AND read_at IS NULL AND created_at > $2",
+		userID, since,
+	).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := r.db.Query(
+		ctx,
+		`
+		SELECT n.id, n.type, n.content, n.resource_id, n.created_at
+		FROM notifications n
+		WHERE n.receiver_id = $1 AND n.read_at IS NULL AND n.created_at > $2
+		ORDER BY n.created_at DESC
+		LIMIT $3
+		`,
+		userID, since, limit,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var notifications []*model.Notification
+	for rows.Next() {
+		var n model.Notification
+		if err := rows.Scan(&n.ID, &n.Type, &n.Content, &n.ResourceID, &n.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		n.ReceiverID = userID
+
+		notifications = append(notifications, &n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
 func (r *notificationRepo) DeleteOldNotifications(ctx context.Context) error {
 	_, err := r.db.Exec(ctx, "DELETE FROM notifications WHERE created_at < NOW() - MAKE_INTERVAL(days => $1)", OLD_NOTIFICATIONS_DAYS)
 	return err
 }
 
-func (r *notificationRepo) CreateGlobalNotification(ctx context.Context, gn model.GlobalNotification) error {
-	_, err := r.db.Exec(ctx, "INSERT INTO global_notifications(id, poster_id, title, content, resource_link) VALUES($1, $2, $3, $4, $5)", gn.ID, gn.PosterID, gn.Title, gn.Content, gn.ResourceLink)
+const globalNotificationColumns = "g.id, g.poster_id, g.title, g.content, g.content_html, g.resource_link, g.variant, g.severity, g.dismissible, g.audience, g.status, g.revision_of, g.submitted_by, g.approved_by, g.updated_by, g.published_at, g.created_at"
+
+func scanGlobalNotification(row pgx.Row) (*model.GlobalNotification, error) {
+	var n model.GlobalNotification
+	if err := row.Scan(
+		&n.ID,
+		&n.PosterID,
+		&n.Title,
+		&n.Content,
+		&n.ContentHTML,
+		&n.ResourceLink,
+		&n.Variant,
+		&n.Severity,
+		&n.Dismissible,
+		&n.Audience,
+		&n.Status,
+		&n.RevisionOf,
+		&n.SubmittedBy,
+		&n.ApprovedBy,
+		&n.UpdatedBy,
+		&n.PublishedAt,
+		&n.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	return &n, nil
+}
+
+func insertGlobalNotificationEvent(ctx context.Context, tx pgx.Tx, event model.GlobalNotificationEvent) error {
+	_, err := tx.Exec(
+		ctx,
+		"INSERT INTO global_notification_events(notification_id, actor_id, action, from_status, to_status, comment) VALUES($1, $2, $3, $4, $5, $6)",
+		event.NotificationID, event.ActorID, event.Action, event.FromStatus, event.ToStatus, event.Comment,
+	)
 	return err
 }
 
+func (r *notificationRepo) CreateGlobalNotification(ctx context.Context, gn model.GlobalNotification) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	if err := tx.QueryRow(
+		ctx,
+		`
+		INSERT INTO global_notifications(poster_id, title, content, content_html, resource_link, variant, severity, dismissible, audience, status, revision_of)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+		`,
+		gn.PosterID, gn.Title, gn.Content, gn.ContentHTML, gn.ResourceLink, gn.Variant, gn.Severity, gn.Dismissible, gn.Audience, model.GLOBAL_NOTIFICATION_STATUS_DRAFT, gn.RevisionOf,
+	).Scan(&id); err != nil {
+		return 0, err
+	}
+
+	if err := insertGlobalNotificationEvent(ctx, tx, model.GlobalNotificationEvent{
+		NotificationID: id,
+		ActorID: gn.PosterID,
+		Action: model.GLOBAL_NOTIFICATION_ACTION_CREATED,
+		ToStatus: model.GLOBAL_NOTIFICATION_STATUS_DRAFT,
+	}); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *notificationRepo) CountGlobalNotificationAudience(ctx context.Context, audience *model.GlobalNotificationAudience) (int64, error) {
+	conditions, args := audienceConditions(audience, nil)
+
+	var count int64
+	if err := r.db.QueryRow(ctx, "SELECT COUNT(*) FROM users u WHERE true"+conditions, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func (r *notificationRepo) GetGlobalNotifications(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.GlobalNotification, error) {
 	if limit > GET_NOTIFICATIONS_MAX_LIMIT {
 		limit = GET_NOTIFICATIONS_MAX_LIMIT
@@ -144,16 +289,21 @@ func (r *notificationRepo) GetGlobalNotifications(ctx context.Context, userID uu
 	rows, err := r.db.Query(
 		ctx,
 		`
-		SELECT g.id, g.poster_id, g.title, g.content, g.resource_link, g.created_at
+		SELECT g.id, g.poster_id, g.title, g.content, g.content_html, g.resource_link, g.variant, g.severity, g.dismissible, g.published_at, g.created_at
 		FROM global_notifications g
 		LEFT JOIN checked_global_notifications c
 			ON c.user_id = $1 AND c.notification_id = g.id
 		WHERE c.notification_id IS NULL
-		ORDER BY g.created_at DESC
+			AND g.status = $4
+			AND (
+				g.audience IS NULL
+				OR EXISTS (SELECT 1 FROM global_notification_recipients r WHERE r.notification_id = g.id AND r.user_id = $1)
+			)
+		ORDER BY g.published_at DESC
 		LIMIT $2
 		OFFSET $3
 		`,
-		userID, limit, offset,
+		userID, limit, offset, model.GLOBAL_NOTIFICATION_STATUS_PUBLISHED,
 	)
 	if err != nil {
 		return nil, err
@@ -163,7 +313,7 @@ func (r *notificationRepo) GetGlobalNotifications(ctx context.Context, userID uu
 	var notifications []*model.GlobalNotification
 	for rows.Next() {
 		var n model.GlobalNotification
-		if err := rows.Scan(&n.ID, &n.PosterID, &n.Title, &n.Content, &n.ResourceLink, &n.CreatedAt); err != nil {
+		if err := rows.Scan(&n.ID, &n.PosterID, &n.Title, &n.Content, &n.ContentHTML, &n.ResourceLink, &n.Variant, &n.Severity, &n.Dismissible, &n.PublishedAt, &n.CreatedAt); err != nil {
 			return nil, err
 		}
 
@@ -181,3 +331,343 @@ func (r *notificationRepo) MarkGlobalNotificationAsRead(ctx context.Context, use
 	_, err := r.db.Exec(ctx, "INSERT INTO checked_global_notifications(user_id, notification_id) VALUES($1, $2)", userID, notificationID)
 	return err
 }
+
+func (r *notificationRepo) FindGlobalNotificationByID(ctx context.Context, id int64) (*model.GlobalNotification, error) {
+	return scanGlobalNotification(r.db.QueryRow(ctx, "SELECT "+globalNotificationColumns+" FROM global_notifications g WHERE g.id = $1", id))
+}
+
+// FindGlobalNotificationRevision returns the revision staged for the published notification,
+// or pgx.ErrNoRows if there is none
+func (r *notificationRepo) FindGlobalNotificationRevision(ctx context.Context, id int64) (*model.GlobalNotification, error) {
+	return scanGlobalNotification(r.db.QueryRow(ctx, "SELECT "+globalNotificationColumns+" FROM global_notifications g WHERE g.revision_of = $1 ORDER BY g.id LIMIT 1", id))
+}
+
+func (r *notificationRepo) ListGlobalNotifications(ctx context.Context, status string, limit, offset int) ([]*model.GlobalNotification, error) {
+	if limit > GET_NOTIFICATIONS_MAX_LIMIT {
+		limit = GET_NOTIFICATIONS_MAX_LIMIT
+	}
+
+	rows, err := r.db.Query(
+		ctx,
+		`
+		SELECT `+globalNotificationColumns+`
+		FROM global_notifications g
+		WHERE $1 = '' OR g.status = $1
+		ORDER BY g.created_at DESC
+		LIMIT $2
+		OFFSET $3
+		`,
+		status, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*model.GlobalNotification
+	for rows.Next() {
+		n, err := scanGlobalNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, n)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// UpdateGlobalNotification updates the content, records event.ActorID as its last editor and moves the notification to event.ToStatus.
+// It returns pgx.ErrNoRows if the notification isn't in event.FromStatus anymore.
+func (r *notificationRepo) UpdateGlobalNotification(ctx context.Context, gn model.GlobalNotification, event model.GlobalNotificationEvent) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	if err := tx.QueryRow(
+		ctx,
+		`
+		UPDATE global_notifications
+		SET title = $1, content = $2, content_html = $3, resource_link = $4, variant = $5, severity = $6, dismissible = $7,
+			status = $8,
+			submitted_by = CASE WHEN $8 = $9 THEN NULL ELSE submitted_by END,
+			approved_by = CASE WHEN $8 = $9 THEN NULL ELSE approved_by END,
+			updated_by = $12
+		WHERE id = $10 AND status = $11
+		RETURNING id
+		`,
+		gn.Title, gn.Content, gn.ContentHTML, gn.ResourceLink, gn.Variant, gn.Severity, gn.Dismissible,
+		event.ToStatus, model.GLOBAL_NOTIFICATION_STATUS_DRAFT,
+		gn.ID, event.FromStatus, event.ActorID,
+	).Scan(&id); err != nil {
+		return err
+	}
+
+	if err := insertGlobalNotificationEvent(ctx, tx, event); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// TransitionGlobalNotification moves the notification from event.FromStatus to event.ToStatus and records the event.
+// Publishing materializes the audience into global_notification_recipients. Publishing a revision
+// copies its content to the notification it revises and deletes it instead.
+// Approving follows the two-person rule: neither the poster, the submitter nor the last editor can approve.
+// It returns pgx.ErrNoRows if the notification isn't in event.FromStatus anymore or the actor can't approve it.
+func (r *notificationRepo) TransitionGlobalNotification(ctx context.Context, event model.GlobalNotificationEvent) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		audience   *model.GlobalNotificationAudience
+		revisionOf *int64
+	)
+	if err := tx.QueryRow(
+		ctx,
+		`
+		UPDATE global_notifications
+		SET status = $1,
+			submitted_by = CASE WHEN $1 = $4 THEN $3 WHEN $1 = $5 THEN NULL ELSE submitted_by END,
+			approved_by = CASE WHEN $1 = $6 THEN $3 WHEN $1 = $5 THEN NULL ELSE approved_by END,
+			published_at = CASE WHEN $1 = $7 THEN NOW() ELSE published_at END
+		WHERE id = $2 AND status = $8
+			AND ($1 <> $6 OR (poster_id <> $3 AND submitted_by IS DISTINCT FROM $3 AND updated_by IS DISTINCT FROM $3))
+		RETURNING audience, revision_of
+		`,
+		event.ToStatus, event.NotificationID, event.ActorID,
+		model.GLOBAL_NOTIFICATION_STATUS_PENDING_APPROVAL,
+		model.GLOBAL_NOTIFICATION_STATUS_DRAFT,
+		model.GLOBAL_NOTIFICATION_STATUS_APPROVED,
+		model.GLOBAL_NOTIFICATION_STATUS_PUBLISHED,
+		event.FromStatus,
+	).Scan(&audience, &revisionOf); err != nil {
+		return err
+	}
+
+	if event.ToStatus == model.GLOBAL_NOTIFICATION_STATUS_PUBLISHED && revisionOf != nil {
+		return publishGlobalNotificationRevision(ctx, tx, event, *revisionOf)
+	}
+
+	if event.ToStatus == model.GLOBAL_NOTIFICATION_STATUS_PUBLISHED && !audience.IsEveryone() {
+		conditions, args := audienceConditions(audience, []interface{}{event.NotificationID})
+		if _, err := tx.Exec(
+			ctx,
+			"INSERT INTO global_notification_recipients(notification_id, user_id) SELECT $1, u.id FROM users u WHERE true"+conditions,
+			args...,
+		); err != nil {
+			return err
+		}
+	}
+
+	if err := insertGlobalNotificationEvent(ctx, tx, event); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// publishGlobalNotificationRevision replaces the live content of the published notification
+// with the revision's, deletes the revision and commits
+func publishGlobalNotificationRevision(ctx context.Context, tx pgx.Tx, event model.GlobalNotificationEvent, revisionOf int64) error {
+	if _, err := tx.Exec(
+		ctx,
+		`
+		UPDATE global_notifications g
+		SET title = r.title, content = r.content, content_html = r.content_html, resource_link = r.resource_link,
+			variant = r.variant, severity = r.severity, dismissible = r.dismissible
+		FROM global_notifications r
+		WHERE r.id = $1 AND g.id = $2
+		`,
+		event.NotificationID, revisionOf,
+	); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM global_notifications WHERE id = $1", event.NotificationID); err != nil {
+		return err
+	}
+
+	if err := insertGlobalNotificationEvent(ctx, tx, event); err != nil {
+		return err
+	}
+
+	if err := insertGlobalNotificationEvent(ctx, tx, model.GlobalNotificationEvent{
+		NotificationID: revisionOf,
+		ActorID: event.ActorID,
+		Action: model.GLOBAL_NOTIFICATION_ACTION_REVISED,
+		FromStatus: model.GLOBAL_NOTIFICATION_STATUS_PUBLISHED,
+		ToStatus: model.GLOBAL_NOTIFICATION_STATUS_PUBLISHED,
+		Comment: fmt.Sprintf("published revision %d", event.NotificationID),
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *notificationRepo) DeleteGlobalNotification(ctx context.Context, event model.GlobalNotificationEvent) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "DELETE FROM checked_global_notifications WHERE notification_id = $1", event.NotificationID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM global_notification_recipients WHERE notification_id = $1", event.NotificationID); err != nil {
+		return err
+	}
+
+	// staged edits go with the notification they revise
+	if _, err := tx.Exec(ctx, "DELETE FROM global_notifications WHERE revision_of = $1", event.NotificationID); err != nil {
+		return err
+	}
+
+	tag, err := tx.Exec(ctx, "DELETE FROM global_notifications WHERE id = $1", event.NotificationID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	if err := insertGlobalNotificationEvent(ctx, tx, event); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *notificationRepo) GetGlobalNotificationEvents(ctx context.Context, notificationID int64) ([]*model.GlobalNotificationEvent, error) {
+	rows, err := r.db.Query(
+		ctx,
+		`
+		SELECT e.id, e.notification_id, e.actor_id, e.action, e.from_status, e.to_status, e.comment, e.created_at
+		FROM global_notification_events e
+		WHERE e.notification_id = $1
+		ORDER BY e.created_at, e.id
+		`,
+		notificationID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.GlobalNotificationEvent
+	for rows.Next() {
+		var e model.GlobalNotificationEvent
+		if err := rows.Scan(&e.ID, &e.NotificationID, &e.ActorID, &e.Action, &e.FromStatus, &e.ToStatus, &e.Comment, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		events = append(events, &e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func (r *notificationRepo) GetGlobalNotificationStats(ctx context.Context, gn model.GlobalNotification, interval string) (*model.GlobalNotificationStats, error) {
+	stats := model.GlobalNotificationStats{
+		NotificationID: gn.ID,
+		Timeline: []*model.GlobalNotificationReadBucket{},
+	}
+
+	audienceQuery := "SELECT COUNT(*) FROM users"
+	audienceArgs := []interface{}{}
+	if gn.Audience != nil {
+		audienceQuery = "SELECT COUNT(*) FROM global_notification_recipients WHERE notification_id = $1"
+		audienceArgs = append(audienceArgs, gn.ID)
+	}
+	if err := r.db.QueryRow(ctx, audienceQuery, audienceArgs...).Scan(&stats.AudienceSize); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(
+		ctx,
+		`
+		SELECT date_trunc($2, c.checked_at) AS bucket, COUNT(*)
+		FROM checked_global_notifications c
+		WHERE c.notification_id = $1
+		GROUP BY bucket
+		ORDER BY bucket
+		`,
+		gn.ID, interval,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b model.GlobalNotificationReadBucket
+		if err := rows.Scan(&b.Bucket, &b.Reads); err != nil {
+			return nil, err
+		}
+
+		stats.ReadCount += b.Reads
+		b.CumulativeReads = stats.ReadCount
+		b.ReadRate = readRate(b.CumulativeReads, stats.AudienceSize)
+
+		stats.Timeline = append(stats.Timeline, &b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats.ReadRate = readRate(stats.ReadCount, stats.AudienceSize)
+
+	return &stats, nil
+}
+
+func readRate(reads, audienceSize int64) float64 {
+	if audienceSize == 0 {
+		return 0
+	}
+	return float64(reads) / float64(audienceSize)
+}
+
+func (r *notificationRepo) FilterGlobalNotificationRecipients(ctx context.Context, notificationID int64, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(
+		ctx,
+		"SELECT user_id FROM global_notification_recipients WHERE notification_id = $1 AND user_id = ANY($2)",
+		notificationID, userIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recipients []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
@@ -0,0 +1,72 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type preferencesRepo struct {
+	db *pgxpool.Pool
+}
+
+func newPreferencesRepo(db *pgxpool.Pool) Preferences {
+	return &preferencesRepo{
+		db: db,
+	}
+}
+
+func (r *preferencesRepo) Get(ctx context.Context, userID uuid.UUID) (*model.NotificationPreferences, error) {
+	var prefs model.NotificationPreferences
+	if err := r.db.QueryRow(
+		ctx,
+		"SELECT p.user_id, p.digest_frequency, p.timezone, p.push_enabled, p.quiet_hours_start, p.quiet_hours_end, p.updated_at FROM notification_preferences p WHERE p.user_id = $1",
+		userID,
+	).Scan(&prefs.UserID, &prefs.DigestFrequency, &prefs.Timezone, &prefs.PushEnabled, &prefs.QuietHoursStart, &prefs.QuietHoursEnd, &prefs.UpdatedAt); err != nil {
+		return nil, err
+	}
+
+	return &prefs, nil
+}
+
+// GetMany returns the preferences of the users that saved any, the others use the defaults
+func (r *preferencesRepo) GetMany(ctx context.Context, userIDs []uuid.UUID) ([]*model.NotificationPreferences, error) {
+	rows, err := r.db.Query(
+		ctx,
+		"SELECT p.user_id, p.digest_frequency, p.timezone, p.push_enabled, p.quiet_hours_start, p.quiet_hours_end, p.updated_at FROM notification_preferences p WHERE p.user_id = ANY($1)",
+		userIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prefs []*model.NotificationPreferences
+	for rows.Next() {
+		var p model.NotificationPreferences
+		if err := rows.Scan(&p.UserID, &p.DigestFrequency, &p.Timezone, &p.PushEnabled, &p.QuietHoursStart, &p.QuietHoursEnd, &p.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		prefs = append(prefs, &p)
+	}
+
+	return prefs, rows.Err()
+}
+
+func (r *preferencesRepo) Upsert(ctx context.Context, prefs model.NotificationPreferences) error {
+	_, err := r.db.Exec(
+		ctx,
+		`
+		INSERT INTO notification_preferences(user_id, digest_frequency, timezone, push_enabled, quiet_hours_start, quiet_hours_end, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (user_id) DO UPDATE
+		SET digest_frequency = EXCLUDED.digest_frequency, timezone = EXCLUDED.timezone, push_enabled = EXCLUDED.push_enabled,
+			quiet_hours_start = EXCLUDED.quiet_hours_start, quiet_hours_end = EXCLUDED.quiet_hours_end, updated_at = NOW()
+		`,
+		prefs.UserID, prefs.DigestFrequency, prefs.Timezone, prefs.PushEnabled, prefs.QuietHoursStart, prefs.QuietHoursEnd,
+	)
+	return err
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type pushRepo struct {
+	db *pgxpool.Pool
+}
+
+func newPushRepo(db *pgxpool.Pool) Push {
+	return &pushRepo{
+		db: db,
+	}
+}
+
+// CreateSubscription stores the subscription. An endpoint belongs to one browser,
+// so subscribing it again moves it to the current user and refreshes its keys.
+func (r *pushRepo) CreateSubscription(ctx context.Context, sub model.PushSubscription) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(
+		ctx,
+		`
+		INSERT INTO push_subscriptions(user_id, endpoint, p256dh, auth, user_agent, created_at)
+		VALUES($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (endpoint) DO UPDATE
+		SET user_id = EXCLUDED.user_id, p256dh = EXCLUDED.p256dh, auth = EXCLUDED.auth, user_agent = EXCLUDED.user_agent
+		RETURNING id
+		`,
+		sub.UserID, sub.Endpoint, sub.P256dh, sub.Auth, sub.UserAgent,
+	).Scan(&id)
+	return id, err
+}
+
+func (r *pushRepo) GetUserSubscriptions(ctx context.Context, userID uuid.UUID) ([]*model.PushSubscription, error) {
+	rows, err := r.db.Query(
+		ctx,
+		"SELECT s.id, s.user_id, s.endpoint, s.p256dh, s.auth, s.user_agent, s.created_at FROM push_subscriptions s WHERE s.user_id = $1 ORDER BY s.created_at DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subs []*model.PushSubscription
+	for rows.Next() {
+		var s model.PushSubscription
+		if err := rows.Scan(&s.ID, &s.UserID, &s.Endpoint, &s.P256dh, &s.Auth, &s.UserAgent, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		subs = append(subs, &s)
+	}
+
+	return subs, rows.Err()
+}
+
+// GetSubscribedUsers returns which of the users have any push subscription
+func (r *pushRepo) GetSubscribedUsers(ctx context.Context, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(
+		ctx,
+		"SELECT DISTINCT s.user_id FROM push_subscriptions s WHERE s.user_id = ANY($1)",
+		userIDs,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users = append(users, id)
+	}
+
+	return users, rows.Err()
+}
+
+func (r *pushRepo) DeleteSubscription(ctx context.Context, userID uuid.UUID, id int64) error {
+	var deleted int64
+	return r.db.QueryRow(
+		ctx,
+		"DELETE FROM push_subscriptions WHERE id = $1 AND user_id = $2 RETURNING id",
+		id, userID,
+	).Scan(&deleted)
+}
+
+func (r *pushRepo) DeleteSubscriptionByEndpoint(ctx context.Context, endpoint string) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM push_subscriptions WHERE endpoint = $1", endpoint)
+	return err
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"time"
 
 	"github.com/BloggingApp/notification-service/internal/model"
 	"github.com/google/uuid"
@@ -22,20 +23,116 @@ type Notification interface {
 	CreateBatch(ctx context.Context, notifications []model.Notification) error
 	CreateBatched(ctx context.Context, notifications []model.Notification, batchSize int) error
 	GetUserNotifications(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]*model.Notification, error)
+	MarkAsRead(ctx context.Context, userID uuid.UUID, notificationID int64) error
+	GetUnreadNotificationsSince(ctx context.Context, userID uuid.UUID, since time.Time, limit int) ([]*model.Notification, int64, error)
+	CountUnread(ctx context.Context, userID uuid.UUID) (int64, error)
 	DeleteOldNotifications(ctx context.Context) error
-	CreateGlobalNotification(ctx context.Context, gn model.GlobalNotification) error
+	CreateGlobalNotification(ctx context.Context, gn model.GlobalNotification) (int64, error)
+	CountGlobalNotificationAudience(ctx context.Context, audience *model.GlobalNotificationAudience) (int64, error)
 	GetGlobalNotifications(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.GlobalNotification, error)
 	MarkGlobalNotificationAsRead(ctx context.Context, userID uuid.UUID, notificationID int64) error
+	FindGlobalNotificationByID(ctx context.Context, id int64) (*model.GlobalNotification, error)
+	FindGlobalNotificationRevision(ctx context.Context, id int64) (*model.GlobalNotification, error)
+	ListGlobalNotifications(ctx context.Context, status string, limit, offset int) ([]*model.GlobalNotification, error)
+	UpdateGlobalNotification(ctx context.Context, gn model.GlobalNotification, event model.GlobalNotificationEvent) error
+	TransitionGlobalNotification(ctx context.Context, event model.GlobalNotificationEvent) error
+	DeleteGlobalNotification(ctx context.Context, event model.GlobalNotificationEvent) error
+	GetGlobalNotificationEvents(ctx context.Context, notificationID int64) ([]*model.GlobalNotificationEvent, error)
+	GetGlobalNotificationStats(ctx context.Context, gn model.GlobalNotification, interval string) (*model.GlobalNotificationStats, error)
+	FilterGlobalNotificationRecipients(ctx context.Context, notificationID int64, userIDs []uuid.UUID) ([]uuid.UUID, error)
+}
+
+type Preferences interface {
+	Get(ctx context.Context, userID uuid.UUID) (*model.NotificationPreferences, error)
+	GetMany(ctx context.Context, userIDs []uuid.UUID) ([]*model.NotificationPreferences, error)
+	Upsert(ctx context.Context, prefs model.NotificationPreferences) error
+}
+
+type Digest interface {
+	GetRecipients(ctx context.Context, localHour int) ([]*model.DigestRecipient, error)
+	Claim(ctx context.Context, userID uuid.UUID, periodKey string, lease time.Duration) (bool, error)
+	Release(ctx context.Context, userID uuid.UUID, periodKey string) error
+	MarkSent(ctx context.Context, userID uuid.UUID, periodKey string, notificationsCount int64) error
+}
+
+type Suppression interface {
+	Add(ctx context.Context, suppression model.EmailSuppression) error
+	IsSuppressed(ctx context.Context, email string) (bool, error)
+	List(ctx context.Context, email string, limit, offset int) ([]*model.EmailSuppression, error)
+	Delete(ctx context.Context, email string) error
+}
+
+type EmailAudit interface {
+	Create(ctx context.Context, record model.EmailAuditRecord) error
+	Search(ctx context.Context, recipient string, from, to time.Time, limit, offset int) ([]*model.EmailAuditRecord, error)
+	DeleteOldRecords(ctx context.Context) error
+}
+
+type Push interface {
+	CreateSubscription(ctx context.Context, sub model.PushSubscription) (int64, error)
+	GetUserSubscriptions(ctx context.Context, userID uuid.UUID) ([]*model.PushSubscription, error)
+	GetSubscribedUsers(ctx context.Context, userIDs []uuid.UUID) ([]uuid.UUID, error)
+	DeleteSubscription(ctx context.Context, userID uuid.UUID, id int64) error
+	DeleteSubscriptionByEndpoint(ctx context.Context, endpoint string) error
+}
+
+type Device interface {
+	CreateToken(ctx context.Context, device model.DeviceToken) (int64, error)
+	GetUserTokens(ctx context.Context, userID uuid.UUID) ([]*model.DeviceToken, error)
+	GetUsersWithTokens(ctx context.Context, userIDs []uuid.UUID, platforms []string) ([]uuid.UUID, error)
+	DeleteToken(ctx context.Context, userID uuid.UUID, id int64) error
+	DeleteInvalidToken(ctx context.Context, token string) error
+}
+
+type Webhook interface {
+	Create(ctx context.Context, webhook model.Webhook) (*model.Webhook, error)
+	FindByID(ctx context.Context, id int64) (*model.Webhook, error)
+	GetUserWebhooks(ctx context.Context, userID uuid.UUID) ([]*model.Webhook, error)
+	GetSubscribed(ctx context.Context, userIDs []uuid.UUID, notificationType string) ([]*model.Webhook, error)
+	Update(ctx context.Context, webhook model.Webhook) error
+	Delete(ctx context.Context, userID uuid.UUID, id int64) error
+	RecordDelivery(ctx context.Context, delivery model.WebhookDelivery, maxFailures int) (bool, error)
+	GetDeliveries(ctx context.Context, webhookID int64, limit, offset int) ([]*model.WebhookDelivery, error)
+	DeleteOldDeliveries(ctx context.Context) error
+}
+
+type SMS interface {
+	Create(ctx context.Context, msg model.SMSMessage) error
+	UpdateStatus(ctx context.Context, messageID string, status string, detail string) error
+}
+
+type Routing interface {
+	CreateDecisions(ctx context.Context, decisions []model.RoutingDecision) error
+	GetUserDecisions(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.RoutingDecision, error)
+	DeleteOldDecisions(ctx context.Context) error
 }
 
 type PGRepo struct {
 	User
 	Notification
+	Preferences
+	Digest
+	Suppression
+	EmailAudit
+	Push
+	Device
+	Webhook
+	SMS
+	Routing
 }
 
 func New(db *pgxpool.Pool) *PGRepo {
 	return &PGRepo{
 		User: newUserRepo(db),
 		Notification: newNotificationRepo(db),
+		Preferences: newPreferencesRepo(db),
+		Digest: newDigestRepo(db),
+		Suppression: newSuppressionRepo(db),
+		EmailAudit: newEmailAuditRepo(db),
+		Push: newPushRepo(db),
+		Device: newDeviceRepo(db),
+		Webhook: newWebhookRepo(db),
+		SMS: newSMSRepo(db),
+		Routing: newRoutingRepo(db),
 	}
 }
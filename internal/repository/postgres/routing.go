@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	GET_ROUTING_DECISIONS_MAX_LIMIT = 100
+	OLD_ROUTING_DECISIONS_DAYS = 7
+)
+
+type routingRepo struct {
+	db *pgxpool.Pool
+}
+
+func newRoutingRepo(db *pgxpool.Pool) Routing {
+	return &routingRepo{
+		db: db,
+	}
+}
+
+// CreateDecisions inserts the decisions of a routed batch in one statement
+func (r *routingRepo) CreateDecisions(ctx context.Context, decisions []model.RoutingDecision) error {
+	if len(decisions) == 0 {
+		return nil
+	}
+
+	var (
+		userIDs     = make([]uuid.UUID, len(decisions))
+		types       = make([]string, len(decisions))
+		resourceIDs = make([]string, len(decisions))
+		priorities  = make([]string, len(decisions))
+		channels    = make([]string, len(decisions))
+		reasons     = make([]string, len(decisions))
+		online      = make([]bool, len(decisions))
+		quietHours  = make([]bool, len(decisions))
+	)
+	for i, d := range decisions {
+		userIDs[i] = d.UserID
+		types[i] = d.Type
+		resourceIDs[i] = d.ResourceID
+		priorities[i] = d.Priority
+		channels[i] = d.Channel
+		reasons[i] = d.Reason
+		online[i] = d.Online
+		quietHours[i] = d.QuietHours
+	}
+
+	_, err := r.db.Exec(
+		ctx,
+		`
+		INSERT INTO notification_routing_decisions(user_id, type, resource_id, priority, channel, reason, online, quiet_hours, created_at)
+		SELECT d.user_id, d.type, d.resource_id, d.priority, d.channel, d.reason, d.online, d.quiet_hours, NOW()
+		FROM unnest($1::uuid[], $2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::bool[], $8::bool[])
+			AS d(user_id, type, resource_id, priority, channel, reason, online, quiet_hours)
+		`,
+		userIDs, types, resourceIDs, priorities, channels, reasons, online, quietHours,
+	)
+	return err
+}
+
+func (r *routingRepo) GetUserDecisions(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.RoutingDecision, error) {
+	if limit > GET_ROUTING_DECISIONS_MAX_LIMIT {
+		limit = GET_ROUTING_DECISIONS_MAX_LIMIT
+	}
+
+	rows, err := r.db.Query(
+		ctx,
+		`
+		SELECT d.id, d.user_id, d.type, d.resource_id, d.priority, d.channel, d.reason, d.online, d.quiet_hours, d.created_at
+		FROM notification_routing_decisions d
+		WHERE d.user_id = $1
+		ORDER BY d.created_at DESC
+		LIMIT $2
+		OFFSET $3
+		`,
+		userID, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var decisions []*model.RoutingDecision
+	for rows.Next() {
+		var d model.RoutingDecision
+		if err := rows.Scan(&d.ID, &d.UserID, &d.Type, &d.ResourceID, &d.Priority, &d.Channel, &d.Reason, &d.Online, &d.QuietHours, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		decisions = append(decisions, &d)
+	}
+
+	return decisions, rows.Err()
+}
+
+func (r *routingRepo) DeleteOldDecisions(ctx context.Context) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM notification_routing_decisions WHERE created_at < NOW() - MAKE_INTERVAL(days => $1)", OLD_ROUTING_DECISIONS_DAYS)
+	return err
+}
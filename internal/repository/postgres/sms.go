@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type smsRepo struct {
+	db *pgxpool.Pool
+}
+
+func newSMSRepo(db *pgxpool.Pool) SMS {
+	return &smsRepo{
+		db: db,
+	}
+}
+
+func (r *smsRepo) Create(ctx context.Context, msg model.SMSMessage) error {
+	_, err := r.db.Exec(
+		ctx,
+		`
+		INSERT INTO sms_messages(message_id, type, phone, status, detail, attempt, created_at, updated_at)
+		VALUES($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		`,
+		msg.MessageID, msg.Type, msg.Phone, msg.Status, msg.Detail, msg.Attempt,
+	)
+	return err
+}
+
+// UpdateStatus applies a delivery status reported by the gateway
+func (r *smsRepo) UpdateStatus(ctx context.Context, messageID string, status string, detail string) error {
+	var id int64
+	return r.db.QueryRow(
+		ctx,
+		"UPDATE sms_messages SET status = $1, detail = $2, updated_at = NOW() WHERE message_id = $3 RETURNING id",
+		status, detail, messageID,
+	).Scan(&id)
+}
@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	GET_SUPPRESSIONS_MAX_LIMIT = 100
+)
+
+type suppressionRepo struct {
+	db *pgxpool.Pool
+}
+
+func newSuppressionRepo(db *pgxpool.Pool) Suppression {
+	return &suppressionRepo{
+		db: db,
+	}
+}
+
+// Add suppresses the address or refreshes the reason of an existing suppression.
+// Addresses are compared case-insensitively.
+func (r *suppressionRepo) Add(ctx context.Context, suppression model.EmailSuppression) error {
+	_, err := r.db.Exec(
+		ctx,
+		`
+		INSERT INTO email_suppressions(email, reason, detail, created_at, updated_at)
+		VALUES(LOWER($1), $2, $3, NOW(), NOW())
+		ON CONFLICT (email) DO UPDATE
+		SET reason = EXCLUDED.reason, detail = EXCLUDED.detail, updated_at = NOW()
+		`,
+		suppression.Email, suppression.Reason, suppression.Detail,
+	)
+	return err
+}
+
+func (r *suppressionRepo) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	var suppressed bool
+	err := r.db.QueryRow(
+		ctx,
+		"SELECT EXISTS(SELECT 1 FROM email_suppressions WHERE email = LOWER($1))",
+		email,
+	).Scan(&suppressed)
+	return suppressed, err
+}
+
+func (r *suppressionRepo) List(ctx context.Context, email string, limit, offset int) ([]*model.EmailSuppression, error) {
+	if limit > GET_SUPPRESSIONS_MAX_LIMIT {
+		limit = GET_SUPPRESSIONS_MAX_LIMIT
+	}
+
+	rows, err := r.db.Query(
+		ctx,
+		`
+		SELECT s.email, s.reason, s.detail, s.created_at, s.updated_at
+		FROM email_suppressions s
+		WHERE $1 = '' OR s.email = LOWER($1)
+		ORDER BY s.updated_at DESC
+		LIMIT $2
+		OFFSET $3
+		`,
+		email, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suppressions []*model.EmailSuppression
+	for rows.Next() {
+		var s model.EmailSuppression
+		if err := rows.Scan(&s.Email, &s.Reason, &s.Detail, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		suppressions = append(suppressions, &s)
+	}
+
+	return suppressions, rows.Err()
+}
+
+func (r *suppressionRepo) Delete(ctx context.Context, email string) error {
+	var deleted string
+	return r.db.QueryRow(
+		ctx,
+		"DELETE FROM email_suppressions WHERE email = LOWER($1) RETURNING email",
+		email,
+	).Scan(&deleted)
+}
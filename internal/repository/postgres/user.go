@@ -21,7 +21,7 @@ func newUserRepo(db *pgxpool.Pool) User {
 }
 
 func (r *userRepo) Create(ctx context.Context, user model.User) error {
-	_, err := r.db.Exec(ctx, "INSERT INTO users(id, username, display_name) VALUES($1, $2, $3)", user.ID, user.Username, user.DisplayName)
+	_, err := r.db.Exec(ctx, "INSERT INTO users(id, username, display_name, email, locale, created_at) VALUES($1, $2, $3, $4, $5, $6)", user.ID, user.Username, user.DisplayName, user.Email, user.Locale, user.CreatedAt)
 	return err
 }
 
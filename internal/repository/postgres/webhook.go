@@ -0,0 +1,203 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	GET_WEBHOOK_DELIVERIES_MAX_LIMIT = 100
+	OLD_WEBHOOK_DELIVERIES_DAYS = 30
+	WEBHOOK_DISABLED_REASON_FAILURES = "too many consecutive failed deliveries"
+)
+
+type webhookRepo struct {
+	db *pgxpool.Pool
+}
+
+func newWebhookRepo(db *pgxpool.Pool) Webhook {
+	return &webhookRepo{
+		db: db,
+	}
+}
+
+const webhookColumns = "w.id, w.user_id, w.url, w.secret, w.types, w.enabled, w.consecutive_failures, w.disabled_reason, w.created_at, w.updated_at"
+
+func scanWebhook(row interface{ Scan(dest ...any) error }) (*model.Webhook, error) {
+	var w model.Webhook
+	if err := row.Scan(&w.ID, &w.UserID, &w.URL, &w.Secret, &w.Types, &w.Enabled, &w.ConsecutiveFailures, &w.DisabledReason, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (r *webhookRepo) Create(ctx context.Context, webhook model.Webhook) (*model.Webhook, error) {
+	return scanWebhook(r.db.QueryRow(
+		ctx,
+		`
+		INSERT INTO webhooks(user_id, url, secret, types, enabled, consecutive_failures, disabled_reason, created_at, updated_at)
+		VALUES($1, $2, $3, $4, TRUE, 0, '', NOW(), NOW())
+		RETURNING id, user_id, url, secret, types, enabled, consecutive_failures, disabled_reason, created_at, updated_at
+		`,
+		webhook.UserID, webhook.URL, webhook.Secret, webhook.Types,
+	))
+}
+
+func (r *webhookRepo) FindByID(ctx context.Context, id int64) (*model.Webhook, error) {
+	return scanWebhook(r.db.QueryRow(ctx, "SELECT "+webhookColumns+" FROM webhooks w WHERE w.id = $1", id))
+}
+
+func (r *webhookRepo) queryWebhooks(ctx context.Context, sql string, args ...any) ([]*model.Webhook, error) {
+	rows, err := r.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*model.Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (r *webhookRepo) GetUserWebhooks(ctx context.Context, userID uuid.UUID) ([]*model.Webhook, error) {
+	return r.queryWebhooks(ctx, "SELECT "+webhookColumns+" FROM webhooks w WHERE w.user_id = $1 ORDER BY w.created_at DESC", userID)
+}
+
+// GetSubscribed returns the users' enabled webhooks that want the notification type
+func (r *webhookRepo) GetSubscribed(ctx context.Context, userIDs []uuid.UUID, notificationType string) ([]*model.Webhook, error) {
+	return r.queryWebhooks(
+		ctx,
+		"SELECT "+webhookColumns+" FROM webhooks w WHERE w.user_id = ANY($1) AND w.enabled AND $2 = ANY(w.types)",
+		userIDs, notificationType,
+	)
+}
+
+// Update saves the url, types and enabled state. Enabling a webhook gives it a fresh start.
+func (r *webhookRepo) Update(ctx context.Context, webhook model.Webhook) error {
+	var id int64
+	return r.db.QueryRow(
+		ctx,
+		`
+		UPDATE webhooks
+		SET url = $1, types = $2, enabled = $3,
+			consecutive_failures = CASE WHEN $3 AND NOT enabled THEN 0 ELSE consecutive_failures END,
+			disabled_reason = CASE WHEN $3 THEN '' ELSE disabled_reason END,
+			updated_at = NOW()
+		WHERE id = $4 AND user_id = $5
+		RETURNING id
+		`,
+		webhook.URL, webhook.Types, webhook.Enabled, webhook.ID, webhook.UserID,
+	).Scan(&id)
+}
+
+func (r *webhookRepo) Delete(ctx context.Context, userID uuid.UUID, id int64) error {
+	var deleted int64
+	return r.db.QueryRow(
+		ctx,
+		"DELETE FROM webhooks WHERE id = $1 AND user_id = $2 RETURNING id",
+		id, userID,
+	).Scan(&deleted)
+}
+
+// RecordDelivery logs the attempt and counts the webhook's consecutive failures, disabling it
+// once they reach maxFailures. It returns whether this attempt disabled the webhook.
+func (r *webhookRepo) RecordDelivery(ctx context.Context, delivery model.WebhookDelivery, maxFailures int) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var enabled bool
+	var failures int
+	if err := tx.QueryRow(
+		ctx,
+		"SELECT enabled, consecutive_failures FROM webhooks WHERE id = $1 FOR UPDATE",
+		delivery.WebhookID,
+	).Scan(&enabled, &failures); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(
+		ctx,
+		`
+		INSERT INTO webhook_deliveries(webhook_id, event_id, type, attempt, success, status_code, response, error, duration_ms, created_at)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		`,
+		delivery.WebhookID, delivery.EventID, delivery.Type, delivery.Attempt, delivery.Success, delivery.StatusCode, delivery.Response, delivery.Error, delivery.DurationMs,
+	); err != nil {
+		return false, err
+	}
+
+	failures++
+	if delivery.Success {
+		failures = 0
+	}
+	disable := enabled && failures >= maxFailures
+
+	if _, err := tx.Exec(
+		ctx,
+		`
+		UPDATE webhooks
+		SET consecutive_failures = $1, enabled = enabled AND NOT $2,
+			disabled_reason = CASE WHEN $2 THEN $3 ELSE disabled_reason END
+		WHERE id = $4
+		`,
+		failures, disable, WEBHOOK_DISABLED_REASON_FAILURES, delivery.WebhookID,
+	); err != nil {
+		return false, err
+	}
+
+	return disable, tx.Commit(ctx)
+}
+
+func (r *webhookRepo) GetDeliveries(ctx context.Context, webhookID int64, limit, offset int) ([]*model.WebhookDelivery, error) {
+	if limit > GET_WEBHOOK_DELIVERIES_MAX_LIMIT {
+		limit = GET_WEBHOOK_DELIVERIES_MAX_LIMIT
+	}
+
+	rows, err := r.db.Query(
+		ctx,
+		`
+		SELECT d.id, d.webhook_id, d.event_id, d.type, d.attempt, d.success, d.status_code, d.response, d.error, d.duration_ms, d.created_at
+		FROM webhook_deliveries d
+		WHERE d.webhook_id = $1
+		ORDER BY d.created_at DESC
+		LIMIT $2
+		OFFSET $3
+		`,
+		webhookID, limit, offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []*model.WebhookDelivery
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.Type, &d.Attempt, &d.Success, &d.StatusCode, &d.Response, &d.Error, &d.DurationMs, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, &d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (r *webhookRepo) DeleteOldDeliveries(ctx context.Context) error {
+	_, err := r.db.Exec(ctx, "DELETE FROM webhook_deliveries WHERE created_at < NOW() - MAKE_INTERVAL(days => $1)", OLD_WEBHOOK_DELIVERIES_DAYS)
+	return err
+}
@@ -0,0 +1,253 @@
+package redisrepo
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+// The presence of a user is a hash with a "state:expires at" field per replica holding their socket,
+// so a user connected to several replicas stays online until the last one lets go, and the fields
+// of a crashed replica stop counting once they expire. The user's state is the best of the fields.
+// The hash also keeps the last state changes were reported for, and a sorted set scores every
+// present user by the next expiry of their fields, so a sweep can report the changes of crashed replicas.
+const presenceScriptPrefix = `
+local now = tonumber(ARGV[1])
+local userID = ARGV[2]
+
+-- settle drops the expired fields and returns the last reported state and the current one
+local function settle(ttl)
+	local fields = redis.call('HGETALL', KEYS[1])
+	local prev = 'offline'
+	local cur = 'offline'
+	local nextExpiry = nil
+	for i = 1, #fields, 2 do
+		if fields[i] == '` + PRESENCE_REPORTED_FIELD + `' then
+			prev = fields[i + 1]
+		else
+			local state, expiresAt = string.match(fields[i + 1], '^(%a+):(%d+)$')
+			expiresAt = tonumber(expiresAt)
+			if not state or expiresAt <= now then
+				redis.call('HDEL', KEYS[1], fields[i])
+			else
+				if state == 'online' then
+					cur = 'online'
+				elseif cur == 'offline' then
+					cur = state
+				end
+				if not nextExpiry or expiresAt < nextExpiry then
+					nextExpiry = expiresAt
+				end
+			end
+		end
+	end
+
+	if cur == 'offline' then
+		redis.call('DEL', KEYS[1])
+		redis.call('ZREM', KEYS[2], userID)
+	else
+		redis.call('HSET', KEYS[1], '` + PRESENCE_REPORTED_FIELD + `', cur)
+		-- outlives the fields, so a sweep still knows the reported state after a crash
+		redis.call('EXPIRE', KEYS[1], ttl * 2)
+		redis.call('ZADD', KEYS[2], nextExpiry, userID)
+	end
+
+	return {prev, cur}
+end
+`
+
+var setPresenceScript = redis.NewScript(presenceScriptPrefix + `
+if ARGV[4] == '' then
+	redis.call('HDEL', KEYS[1], ARGV[3])
+else
+	redis.call('HSET', KEYS[1], ARGV[3], ARGV[4] .. ':' .. ARGV[5])
+end
+
+local result = settle(tonumber(ARGV[6]))
+redis.call('SET', KEYS[3], ARGV[1], 'EX', ARGV[7])
+
+return result
+`)
+
+var sweepPresenceScript = redis.NewScript(presenceScriptPrefix + `
+-- another replica swept the user already or the user's fields were refreshed
+local score = redis.call('ZSCORE', KEYS[2], userID)
+if not score or tonumber(score) > now then
+	return {}
+end
+
+return settle(tonumber(ARGV[3]))
+`)
+
+// PresenceChange is a change of a user's overall state
+type PresenceChange struct {
+	UserID   string
+	Previous string
+	Current  string
+}
+
+// SetPresence sets the replica's state of the user, or removes it when state is empty, and
+// returns the user's state before and after, equal when it didn't change. The user is seen now either way.
+func SetPresence(r *redis.Client, ctx context.Context, userID string, replicaID string, state string, ttl time.Duration, lastSeenTTL time.Duration) (string, string, error) {
+	changes, err := SetPresences(r, ctx, replicaID, map[string]string{userID: state}, ttl, lastSeenTTL)
+	if err != nil {
+		return "", "", err
+	}
+	if len(changes) == 0 {
+		return state, state, nil
+	}
+
+	return changes[0].Previous, changes[0].Current, nil
+}
+
+// SetPresences sets the replica's states of the users in one round trip
+// and returns the changes of the users whose overall state changed
+func SetPresences(r *redis.Client, ctx context.Context, replicaID string, states map[string]string, ttl time.Duration, lastSeenTTL time.Duration) ([]PresenceChange, error) {
+	now := time.Now()
+	return runPresenceScript(r, ctx, setPresenceScript, states, func(userID string, state string) ([]string, []interface{}) {
+		return []string{PresenceKey(userID), PRESENCE_EXPIRY, LastSeenKey(userID)},
+			[]interface{}{now.Unix(), userID, replicaID, state, now.Add(ttl).Unix(), int64(ttl.Seconds()), int64(lastSeenTTL.Seconds())}
+	})
+}
+
+// SweepPresence settles up to limit users with expired fields, which is how the users of a crashed
+// replica go offline, and returns the changes nobody reported yet. Replicas can sweep concurrently,
+// every change is returned to only one of them.
+func SweepPresence(r *redis.Client, ctx context.Context, limit int, ttl time.Duration) ([]PresenceChange, error) {
+	now := time.Now()
+	userIDs, err := r.ZRangeByScore(ctx, PRESENCE_EXPIRY, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.Unix(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil || len(userIDs) == 0 {
+		return nil, err
+	}
+
+	users := make(map[string]string, len(userIDs))
+	for _, userID := range userIDs {
+		users[userID] = ""
+	}
+
+	return runPresenceScript(r, ctx, sweepPresenceScript, users, func(userID string, _ string) ([]string, []interface{}) {
+		return []string{PresenceKey(userID), PRESENCE_EXPIRY}, []interface{}{now.Unix(), userID, int64(ttl.Seconds())}
+	})
+}
+
+// runPresenceScript runs the script for every user in a pipeline. The script is loaded first,
+// a pipeline can't fall back from EVALSHA to EVAL.
+func runPresenceScript(r *redis.Client, ctx context.Context, script *redis.Script, users map[string]string, args func(userID string, state string) ([]string, []interface{})) ([]PresenceChange, error) {
+	if err := script.Load(ctx, r).Err(); err != nil {
+		return nil, err
+	}
+
+	pipe := r.Pipeline()
+	userIDs := make([]string, 0, len(users))
+	cmds := make([]*redis.Cmd, 0, len(users))
+	for userID, state := range users {
+		keys, argv := args(userID, state)
+		userIDs = append(userIDs, userID)
+		cmds = append(cmds, script.EvalSha(ctx, pipe, keys, argv...))
+	}
+	// the users whose script ran still get their changes when others failed
+	_, err := pipe.Exec(ctx)
+
+	var changes []PresenceChange
+	for i, cmd := range cmds {
+		result, cmdErr := cmd.StringSlice()
+		if cmdErr != nil {
+			continue
+		}
+		if len(result) == 2 && result[0] != result[1] {
+			changes = append(changes, PresenceChange{UserID: userIDs[i], Previous: result[0], Current: result[1]})
+		}
+	}
+
+	return changes, err
+}
+
+// GetPresence returns the users' states and last seen times, in the order of userIDs
+func GetPresence(r *redis.Client, ctx context.Context, userIDs []string) ([]string, []*time.Time, error) {
+	pipe := r.Pipeline()
+	stateCmds := make([]*redis.MapStringStringCmd, len(userIDs))
+	lastSeenCmds := make([]*redis.StringCmd, len(userIDs))
+	for i, userID := range userIDs {
+		stateCmds[i] = pipe.HGetAll(ctx, PresenceKey(userID))
+		lastSeenCmds[i] = pipe.Get(ctx, LastSeenKey(userID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().Unix()
+	states := make([]string, len(userIDs))
+	lastSeen := make([]*time.Time, len(userIDs))
+	for i := range userIDs {
+		states[i] = model.PRESENCE_OFFLINE
+		for _, value := range stateCmds[i].Val() {
+			state, ok := liveState(value, now)
+			if !ok {
+				continue
+			}
+			if state == model.PRESENCE_ONLINE || states[i] == model.PRESENCE_OFFLINE {
+				states[i] = state
+			}
+		}
+
+		if seen, err := lastSeenCmds[i].Int64(); err == nil {
+			t := time.Unix(seen, 0).UTC()
+			lastSeen[i] = &t
+		}
+	}
+
+	return states, lastSeen, nil
+}
+
+// GetSocketReplicas returns a replica other than exceptReplicaID holding a socket of each user who has one,
+// preferring a replica where the user is active. Users without such a socket are left out.
+func GetSocketReplicas(r *redis.Client, ctx context.Context, userIDs []string, exceptReplicaID string) (map[string]string, error) {
+	pipe := r.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(userIDs))
+	for i, userID := range userIDs {
+		cmds[i] = pipe.HGetAll(ctx, PresenceKey(userID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	replicas := make(map[string]string)
+	for i, userID := range userIDs {
+		best := model.PRESENCE_OFFLINE
+		for field, value := range cmds[i].Val() {
+			if field == PRESENCE_REPORTED_FIELD || field == exceptReplicaID {
+				continue
+			}
+			state, ok := liveState(value, now)
+			if !ok {
+				continue
+			}
+			if state == model.PRESENCE_ONLINE || best == model.PRESENCE_OFFLINE {
+				best = state
+				replicas[userID] = field
+			}
+		}
+	}
+
+	return replicas, nil
+}
+
+// liveState parses a replica's "state:expires at" field, failing when it's expired
+func liveState(value string, now int64) (string, bool) {
+	state, expiresAt, ok := strings.Cut(value, ":")
+	exp, err := strconv.ParseInt(expiresAt, 10, 64)
+	if !ok || err != nil || exp <= now {
+		return "", false
+	}
+
+	return state, true
+}
@@ -3,9 +3,45 @@ package redisrepo
 import "fmt"
 
 const (
-	USER_NOTIFICATIONS = "user:%s-notifications:%d:%d" // <userID>:<limit>:<offset>
+	USER_NOTIFICATIONS = "user:%s-notifications" // <userID>, a hash of the cached pages
+	USER_NOTIFICATIONS_PAGE = "%d:%d" // <limit>:<offset>
+
+	MAIL_LIMIT = "mail-limit:%s:%d" // <scope>:<window start unix>
+	SMS_LIMIT = "sms-limit:%s:%d" // <phone>:<window start unix>
+
+	PRESENCE = "presence:%s" // <userID>
+	LAST_SEEN = "last-seen:%s" // <userID>
+	PRESENCE_EXPIRY = "presence-expiry" // a sorted set of present users, scored by their fields' next expiry
+	PRESENCE_REPORTED_FIELD = "reported" // the presence hash's field of the last reported state
+
+	GLOBAL_NOTIFICATIONS_CHANNEL = "global-notifications"
+	REPLICA_DELIVERIES_CHANNEL = "replica-deliveries:%s" // <replicaID>, notifications for the sockets the replica holds
 )
 
-func UserNotificationsKey(userID string, limit int, offset int) string {
-	return fmt.Sprintf(USER_NOTIFICATIONS, userID, limit, offset)
+func UserNotificationsKey(userID string) string {
+	return fmt.Sprintf(USER_NOTIFICATIONS, userID)
+}
+
+func UserNotificationsPageField(limit int, offset int) string {
+	return fmt.Sprintf(USER_NOTIFICATIONS_PAGE, limit, offset)
+}
+
+func MailLimitKey(scope string, windowStart int64) string {
+	return fmt.Sprintf(MAIL_LIMIT, scope, windowStart)
+}
+
+func SMSLimitKey(phone string, windowStart int64) string {
+	return fmt.Sprintf(SMS_LIMIT, phone, windowStart)
+}
+
+func PresenceKey(userID string) string {
+	return fmt.Sprintf(PRESENCE, userID)
+}
+
+func LastSeenKey(userID string) string {
+	return fmt.Sprintf(LAST_SEEN, userID)
+}
+
+func ReplicaDeliveriesChannel(replicaID string) string {
+	return fmt.Sprintf(REPLICA_DELIVERIES_CHANNEL, replicaID)
 }
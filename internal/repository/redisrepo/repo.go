@@ -38,3 +38,77 @@ func Get[T any](r *redis.Client, ctx context.Context, key string) (*T, error) {
 
 	return &result, nil
 }
+
+func HGet[T any](r *redis.Client, ctx context.Context, key string, field string) (*T, error) {
+	value, err := r.HGet(ctx, key, field).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if value == "null" {
+		return nil, nil
+	}
+
+	var result T
+	if err := json.Unmarshal([]byte(value), &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// HSetJSON sets the hash field. The hash expires after expiration counted from its first field,
+// so all of its fields can be dropped at once by deleting the key.
+func HSetJSON(r *redis.Client, ctx context.Context, key string, field string, value interface{}, expiration time.Duration) error {
+	valueJSON, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	pipe := r.TxPipeline()
+	pipe.HSet(ctx, key, field, valueJSON)
+	ttl := pipe.TTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	// a new hash has no expiration yet
+	if ttl.Val() < 0 {
+		return r.Expire(ctx, key, expiration).Err()
+	}
+	return nil
+}
+
+func Del(r *redis.Client, ctx context.Context, keys ...string) error {
+	return r.Del(ctx, keys...).Err()
+}
+
+// IncrWindow increments a fixed-window counter, the key expires after the window
+func IncrWindow(r *redis.Client, ctx context.Context, key string, window time.Duration) (int64, error) {
+	pipe := r.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	return incr.Val(), nil
+}
+
+// HitLimit counts an event in the current fixed window of max events per window and tells whether
+// the window is over the limit and when it ends. A zero max or window is unlimited. Limits fail open:
+// when Redis is unavailable the event is allowed and the error is returned only to be logged.
+func HitLimit(r *redis.Client, ctx context.Context, key func(windowStart int64) string, max int, window time.Duration) (bool, time.Time, error) {
+	if max <= 0 || window <= 0 {
+		return false, time.Time{}, nil
+	}
+
+	windowStart := time.Now().Truncate(window)
+	windowEnd := windowStart.Add(window)
+	count, err := IncrWindow(r, ctx, key(windowStart.Unix()), window)
+	if err != nil {
+		return false, windowEnd, err
+	}
+
+	return count > int64(max), windowEnd, nil
+}
@@ -0,0 +1,103 @@
+package routing
+
+import (
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+)
+
+const (
+	CHANNEL_WEBSOCKET = "websocket"
+	CHANNEL_PUSH = "push" // web push and the mobile apps
+	CHANNEL_DIGEST = "digest"
+	CHANNEL_INBOX = "inbox" // only stored, the user sees it next time they open the app
+
+	PRIORITY_LOW = "low"
+	PRIORITY_NORMAL = "normal"
+	PRIORITY_HIGH = "high" // pushed even in quiet hours
+
+	QUIET_HOURS_LAYOUT = "15:04"
+)
+
+// Input is everything a routing decision depends on
+type Input struct {
+	Type           string
+	Priority       string
+	Online         bool // the user has an open socket
+	HasPushTargets bool // the user has a push subscription or a device of a configured platform
+	Preferences    *model.NotificationPreferences
+	Now            time.Time
+}
+
+type Decision struct {
+	Channel    string
+	Reason     string
+	QuietHours bool
+}
+
+// Decide picks the channel for a notification: the socket if the user is online, otherwise push,
+// otherwise their email digest. Every notification is stored either way, so the digest
+// and inbox channels need no sending.
+func Decide(in Input) Decision {
+	if in.Online {
+		return Decision{Channel: CHANNEL_WEBSOCKET, Reason: "user is online"}
+	}
+
+	quiet := InQuietHours(in.Preferences, in.Now)
+	d := fallback(in.Preferences, quiet)
+
+	switch {
+	case in.Priority == PRIORITY_LOW:
+		d.Reason = "low priority notifications are not pushed"
+	case !in.Preferences.PushEnabled:
+		d.Reason = "push is turned off in preferences"
+	case quiet && in.Priority != PRIORITY_HIGH:
+		d.Reason = "quiet hours"
+	case !in.HasPushTargets:
+		d.Reason = "no push subscriptions or devices"
+	default:
+		d.Channel = CHANNEL_PUSH
+		d.Reason = "user is offline"
+		if quiet {
+			d.Reason = "high priority during quiet hours"
+		}
+	}
+
+	return d
+}
+
+func fallback(prefs *model.NotificationPreferences, quiet bool) Decision {
+	if prefs.DigestFrequency != model.DIGEST_FREQUENCY_OFF {
+		return Decision{Channel: CHANNEL_DIGEST, QuietHours: quiet}
+	}
+	return Decision{Channel: CHANNEL_INBOX, QuietHours: quiet}
+}
+
+// InQuietHours tells whether now falls into the user's quiet hours. Quiet hours may wrap
+// past midnight, e.g. 22:00 to 07:00.
+func InQuietHours(prefs *model.NotificationPreferences, now time.Time) bool {
+	if prefs.QuietHoursStart == nil || prefs.QuietHoursEnd == nil {
+		return false
+	}
+
+	start, err0 := time.Parse(QUIET_HOURS_LAYOUT, *prefs.QuietHoursStart)
+	end, err1 := time.Parse(QUIET_HOURS_LAYOUT, *prefs.QuietHoursEnd)
+	if err0 != nil || err1 != nil {
+		return false
+	}
+
+	loc, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	local := now.In(loc)
+
+	minute := local.Hour()*60 + local.Minute()
+	startMinute := start.Hour()*60 + start.Minute()
+	endMinute := end.Hour()*60 + end.Minute()
+
+	if startMinute < endMinute {
+		return minute >= startMinute && minute < endMinute
+	}
+	return minute >= startMinute || minute < endMinute
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/repository/redisrepo"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	BROADCAST_WORKERS = 64
+	BROADCAST_RECIPIENTS_CHUNK = 5000
+)
+
+// globalNotificationBroadcast is sent over redis pub/sub so that every replica
+// pushes the announcement to the sockets it holds.
+type globalNotificationBroadcast struct {
+	ID           int64     `json:"id"`
+	Title        string    `json:"title"`
+	Content      string    `json:"content"`
+	ContentHTML  string    `json:"content_html"`
+	ResourceLink string    `json:"resource_link"`
+	Variant      string    `json:"variant"`
+	Severity     string    `json:"severity"`
+	Dismissible  bool      `json:"dismissible"`
+	Targeted     bool      `json:"targeted"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (s *notificationService) publishGlobalNotificationBroadcast(ctx context.Context, gn model.GlobalNotification) error {
+	broadcastJSON, err := json.Marshal(globalNotificationBroadcast{
+		ID: gn.ID,
+		Title: gn.Title,
+		Content: gn.Content,
+		ContentHTML: gn.ContentHTML,
+		ResourceLink: gn.ResourceLink,
+		Variant: gn.Variant,
+		Severity: gn.Severity,
+		Dismissible: gn.Dismissible,
+		Targeted: gn.Audience != nil,
+		CreatedAt: gn.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.rdb.Publish(ctx, redisrepo.GLOBAL_NOTIFICATIONS_CHANNEL, broadcastJSON).Err()
+}
+
+func (s *notificationService) StartBroadcastingGlobalNotifications(ctx context.Context) {
+	pubsub := s.rdb.Subscribe(ctx, redisrepo.GLOBAL_NOTIFICATIONS_CHANNEL)
+	defer pubsub.Close()
+
+	for msg := range pubsub.Channel() {
+		var broadcast globalNotificationBroadcast
+		if err := json.Unmarshal([]byte(msg.Payload), &broadcast); err != nil {
+			s.logger.Sugar().Errorf("failed to unmarshal data from redis channel(%s) to json: %s", redisrepo.GLOBAL_NOTIFICATIONS_CHANNEL, err.Error())
+			continue
+		}
+
+		s.broadcastGlobalNotification(ctx, broadcast)
+	}
+}
+
+// broadcastGlobalNotification writes the announcement to the sockets this replica holds. It bypasses
+// route on purpose. A global notification is stored once, not per user, and users who weren't online
+// fetch it later through GetGlobalNotifications. So an open socket is its only live channel, and there
+// is no push, digest or quiet hours to decide between. A routing decision per connected user would
+// write a row for every online user on every announcement. It would tell nothing that publishing
+// the announcement and its read stats don't already record.
+func (s *notificationService) broadcastGlobalNotification(ctx context.Context, broadcast globalNotificationBroadcast) {
+	payloadJSON, err := json.Marshal(map[string]interface{}{
+		"type": GLOBAL_NOTIFICATION_TYPE,
+		"id": broadcast.ID,
+		"title": broadcast.Title,
+		"content": broadcast.Content,
+		"content_html": broadcast.ContentHTML,
+		"resource_link": broadcast.ResourceLink,
+		"variant": broadcast.Variant,
+		"severity": broadcast.Severity,
+		"dismissible": broadcast.Dismissible,
+		"created_at": broadcast.CreatedAt,
+	})
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to marshal global notification(%d) payload: %s", broadcast.ID, err.Error())
+		return
+	}
+
+	// the frame is encoded once and shared by every connection
+	pm, err := websocket.NewPreparedMessage(websocket.TextMessage, payloadJSON)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to prepare global notification(%d) message: %s", broadcast.ID, err.Error())
+		return
+	}
+
+	conns := make(map[uuid.UUID]*wsConn)
+	s.conns.Range(func(key, value any) bool {
+		userID, ok := key.(uuid.UUID)
+		if !ok {
+			return true
+		}
+		conn, ok := value.(*wsConn)
+		if !ok {
+			return true
+		}
+		conns[userID] = conn
+		return true
+	})
+
+	if broadcast.Targeted {
+		conns, err = s.filterGlobalNotificationRecipients(ctx, broadcast.ID, conns)
+		if err != nil {
+			s.logger.Sugar().Errorf("failed to filter global notification(%d) recipients: %s", broadcast.ID, err.Error())
+			return
+		}
+	}
+
+	jobs := make(chan *wsConn)
+	var wg sync.WaitGroup
+	for range BROADCAST_WORKERS {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for conn := range jobs {
+				// failed connections are cleaned up by their reader goroutine
+				conn.writePreparedMessage(pm)
+			}
+		}()
+	}
+
+	for _, conn := range conns {
+		jobs <- conn
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func (s *notificationService) filterGlobalNotificationRecipients(ctx context.Context, notificationID int64, conns map[uuid.UUID]*wsConn) (map[uuid.UUID]*wsConn, error) {
+	userIDs := make([]uuid.UUID, 0, len(conns))
+	for userID := range conns {
+		userIDs = append(userIDs, userID)
+	}
+
+	recipients := make(map[uuid.UUID]*wsConn)
+	for i := 0; i < len(userIDs); i += BROADCAST_RECIPIENTS_CHUNK {
+		end := min(i + BROADCAST_RECIPIENTS_CHUNK, len(userIDs))
+
+		ids, err := s.repo.Postgres.Notification.FilterGlobalNotificationRecipients(ctx, notificationID, userIDs[i:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range ids {
+			recipients[id] = conns[id]
+		}
+	}
+
+	return recipients, nil
+}
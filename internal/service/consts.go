@@ -3,4 +3,21 @@ package service
 const (
 	NEW_POST_NOTIFICATION_TYPE = "post"
 	POST_VALIDATION_STATUS_UPDATE_TYPE = "post-validation-status-update"
+	GLOBAL_NOTIFICATION_TYPE = "global"
+
+	GLOBAL_NOTIFICATION_VARIANT_BANNER = "banner"
+	GLOBAL_NOTIFICATION_VARIANT_MODAL = "modal"
+	GLOBAL_NOTIFICATION_VARIANT_INLINE = "inline"
+
+	GLOBAL_NOTIFICATION_SEVERITY_INFO = "info"
+	GLOBAL_NOTIFICATION_SEVERITY_WARNING = "warning"
+	GLOBAL_NOTIFICATION_SEVERITY_CRITICAL = "critical"
+
+	MAX_GLOBAL_NOTIFICATION_CONTENT_LENGTH = 20000
+	MAX_AUDIENCE_USER_IDS = 10000
+	MAX_LOCALE_LENGTH = 35
+
+	STATS_INTERVAL_HOUR = "hour"
+	STATS_INTERVAL_DAY = "day"
+	STATS_INTERVAL_WEEK = "week"
 )
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+	"github.com/google/uuid"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+const (
+	GET_DEAD_LETTERS_MAX_LIMIT = 100
+	REDACTED = "[redacted]"
+)
+
+// deadLetterSecrets are the body fields holding sign-in codes and account links, by queue.
+// Admins see dead letters with them redacted, like the mail audit log leaves the codes out.
+var deadLetterSecrets = map[string][]string{
+	rabbitmq.REGISTRATION_CODE_MAIL_QUEUE: {"code"},
+	rabbitmq.SIGNIN_CODE_MAIL_QUEUE: {"code"},
+	rabbitmq.SIGNIN_CODE_SMS_QUEUE: {"code"},
+	rabbitmq.PASSWORD_RESET_MAIL_QUEUE: {"reset_url"},
+	rabbitmq.EMAIL_CHANGE_MAIL_QUEUE: {"confirm_url"},
+}
+
+type deadLetterService struct {
+	logger *zap.Logger
+	rabbitmq *rabbitmq.MQConn
+}
+
+func newDeadLetterService(logger *zap.Logger, rabbitmq *rabbitmq.MQConn) DeadLetter {
+	return &deadLetterService{
+		logger: logger,
+		rabbitmq: rabbitmq,
+	}
+}
+
+func (s *deadLetterService) GetDeadLetterQueues(ctx context.Context) ([]*model.DeadLetterQueue, error) {
+	var queues []*model.DeadLetterQueue
+	for _, queue := range rabbitmq.ConsumedQueues() {
+		count, err := s.rabbitmq.CountDeadLetters(queue)
+		if err != nil {
+			s.logger.Sugar().Errorf("failed to count dead letters of queue(%s): %s", queue, err.Error())
+			return nil, ErrInternal
+		}
+
+		queues = append(queues, &model.DeadLetterQueue{
+			Queue: queue,
+			DeadLetterQueue: rabbitmq.DeadLetterQueue(queue),
+			Messages: count,
+		})
+	}
+
+	return queues, nil
+}
+
+func (s *deadLetterService) GetDeadLetters(ctx context.Context, queue string, limit int) ([]*model.DeadLetter, error) {
+	if !slices.Contains(rabbitmq.ConsumedQueues(), queue) {
+		return nil, ErrQueueNotFound
+	}
+	if limit <= 0 || limit > GET_DEAD_LETTERS_MAX_LIMIT {
+		limit = GET_DEAD_LETTERS_MAX_LIMIT
+	}
+
+	msgs, err := s.rabbitmq.PeekDeadLetters(queue, limit)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to peek dead letters of queue(%s): %s", queue, err.Error())
+		return nil, ErrInternal
+	}
+
+	deadLetters := make([]*model.DeadLetter, len(msgs))
+	for i, msg := range msgs {
+		deadLetters[i] = deadLetterFromDelivery(queue, msg)
+	}
+
+	return deadLetters, nil
+}
+
+func (s *deadLetterService) GetDeadLetter(ctx context.Context, queue string, id string) (*model.DeadLetter, error) {
+	if !slices.Contains(rabbitmq.ConsumedQueues(), queue) {
+		return nil, ErrQueueNotFound
+	}
+	if id == "" {
+		return nil, ErrInvalidDeadLetterID
+	}
+
+	msg, err := s.rabbitmq.FindDeadLetter(queue, id)
+	if err != nil {
+		if err == rabbitmq.ErrDeadLetterNotFound {
+			return nil, ErrDeadLetterNotFound
+		}
+
+		s.logger.Sugar().Errorf("failed to find dead letter(%s) of queue(%s): %s", id, queue, err.Error())
+		return nil, ErrInternal
+	}
+
+	return deadLetterFromDelivery(queue, *msg), nil
+}
+
+// ReplayDeadLetters sends all of the queue's dead letters back to the queue
+func (s *deadLetterService) ReplayDeadLetters(ctx context.Context, queue string, adminID uuid.UUID) (int, error) {
+	if !slices.Contains(rabbitmq.ConsumedQueues(), queue) {
+		return 0, ErrQueueNotFound
+	}
+
+	replayed, err := s.rabbitmq.ReplayDeadLetters(queue)
+	if replayed > 0 {
+		s.logger.Sugar().Infof("admin(%s) replayed %d dead letter(s) of queue(%s)", adminID.String(), replayed, queue)
+	}
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to replay dead letters of queue(%s): %s", queue, err.Error())
+		return replayed, ErrInternal
+	}
+
+	return replayed, nil
+}
+
+// ReplayDeadLetter sends the dead letter with the id back to the queue
+func (s *deadLetterService) ReplayDeadLetter(ctx context.Context, queue string, id string, adminID uuid.UUID) error {
+	if !slices.Contains(rabbitmq.ConsumedQueues(), queue) {
+		return ErrQueueNotFound
+	}
+	if id == "" {
+		return ErrInvalidDeadLetterID
+	}
+
+	if err := s.rabbitmq.ReplayDeadLetter(queue, id); err != nil {
+		if err == rabbitmq.ErrDeadLetterNotFound {
+			return ErrDeadLetterNotFound
+		}
+
+		s.logger.Sugar().Errorf("failed to replay dead letter(%s) of queue(%s): %s", id, queue, err.Error())
+		return ErrInternal
+	}
+
+	s.logger.Sugar().Infof("admin(%s) replayed dead letter(%s) of queue(%s)", adminID.String(), id, queue)
+
+	return nil
+}
+
+// PurgeDeadLetters drops all of the queue's dead letters
+func (s *deadLetterService) PurgeDeadLetters(ctx context.Context, queue string, adminID uuid.UUID) (int, error) {
+	if !slices.Contains(rabbitmq.ConsumedQueues(), queue) {
+		return 0, ErrQueueNotFound
+	}
+
+	purged, err := s.rabbitmq.PurgeDeadLetters(queue)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to purge dead letters of queue(%s): %s", queue, err.Error())
+		return 0, ErrInternal
+	}
+
+	s.logger.Sugar().Infof("admin(%s) purged %d dead letter(s) of queue(%s)", adminID.String(), purged, queue)
+
+	return purged, nil
+}
+
+// PurgeDeadLetter drops the dead letter with the id
+func (s *deadLetterService) PurgeDeadLetter(ctx context.Context, queue string, id string, adminID uuid.UUID) error {
+	if !slices.Contains(rabbitmq.ConsumedQueues(), queue) {
+		return ErrQueueNotFound
+	}
+	if id == "" {
+		return ErrInvalidDeadLetterID
+	}
+
+	if err := s.rabbitmq.PurgeDeadLetter(queue, id); err != nil {
+		if err == rabbitmq.ErrDeadLetterNotFound {
+			return ErrDeadLetterNotFound
+		}
+
+		s.logger.Sugar().Errorf("failed to purge dead letter(%s) of queue(%s): %s", id, queue, err.Error())
+		return ErrInternal
+	}
+
+	s.logger.Sugar().Infof("admin(%s) purged dead letter(%s) of queue(%s)", adminID.String(), id, queue)
+
+	return nil
+}
+
+func deadLetterFromDelivery(queue string, msg amqp.Delivery) *model.DeadLetter {
+	deadLetter := &model.DeadLetter{
+		ID: rabbitmq.DeadLetterID(msg),
+		Queue: queue,
+		Attempts: rabbitmq.Attempt(msg),
+		ContentType: msg.ContentType,
+		Headers: msg.Headers,
+		Body: redactDeadLetterBody(queue, msg.Body),
+	}
+	if cause, ok := msg.Headers[rabbitmq.ERROR_HEADER].(string); ok {
+		deadLetter.Error = cause
+	}
+	if !msg.Timestamp.IsZero() {
+		deadLetter.DeadLetteredAt = &msg.Timestamp
+	}
+
+	return deadLetter
+}
+
+// redactDeadLetterBody replaces the queue's secret fields of the body. A body of such a queue
+// that isn't a json object is left out, since there is no telling where its secret is.
+func redactDeadLetterBody(queue string, body []byte) string {
+	secrets, ok := deadLetterSecrets[queue]
+	if !ok {
+		return string(body)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return REDACTED
+	}
+
+	redacted, _ := json.Marshal(REDACTED)
+	for _, secret := range secrets {
+		if _, ok := fields[secret]; ok {
+			fields[secret] = redacted
+		}
+	}
+
+	redactedBody, err := json.Marshal(fields)
+	if err != nil {
+		return REDACTED
+	}
+
+	return string(redactedBody)
+}
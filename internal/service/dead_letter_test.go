@@ -0,0 +1,54 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func TestDeadLettersHideCodesAndAccountLinks(t *testing.T) {
+	tests := []struct {
+		queue  string
+		body   string
+		secret string
+	}{
+		{rabbitmq.REGISTRATION_CODE_MAIL_QUEUE, `{"email":"jane@example.com","code":482913}`, "482913"},
+		{rabbitmq.SIGNIN_CODE_MAIL_QUEUE, `{"email":"jane@example.com","code":482913}`, "482913"},
+		{rabbitmq.SIGNIN_CODE_SMS_QUEUE, `{"phone":"+15550100","code":482913}`, "482913"},
+		{rabbitmq.PASSWORD_RESET_MAIL_QUEUE, `{"email":"jane@example.com","reset_url":"https://example.com/reset?token=s3cr3t"}`, "s3cr3t"},
+		{rabbitmq.EMAIL_CHANGE_MAIL_QUEUE, `{"new_email":"jane@example.com","confirm_url":"https://example.com/confirm?token=s3cr3t"}`, "s3cr3t"},
+		{rabbitmq.SIGNIN_CODE_MAIL_QUEUE, `code=482913`, "482913"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.queue, func(t *testing.T) {
+			deadLetter := deadLetterFromDelivery(tt.queue, amqp.Delivery{Body: []byte(tt.body)})
+
+			if strings.Contains(deadLetter.Body, tt.secret) {
+				t.Fatalf("expected the secret redacted, got %s", deadLetter.Body)
+			}
+
+			// the rest of a json body stays readable
+			var fields map[string]any
+			if json.Unmarshal([]byte(tt.body), &fields) == nil {
+				var redacted map[string]any
+				if err := json.Unmarshal([]byte(deadLetter.Body), &redacted); err != nil {
+					t.Fatalf("expected a json body, got %s", deadLetter.Body)
+				}
+				if len(redacted) != len(fields) {
+					t.Fatalf("expected the other fields kept, got %s", deadLetter.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestDeadLettersOfOtherQueuesKeepTheirBody(t *testing.T) {
+	body := `{"user_id":"1","follower_id":"2"}`
+	if deadLetter := deadLetterFromDelivery(rabbitmq.FOLLOWS_QUEUE, amqp.Delivery{Body: []byte(body)}); deadLetter.Body != body {
+		t.Fatalf("expected the body as is, got %s", deadLetter.Body)
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/BloggingApp/notification-service/internal/mobilepush"
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	MAX_DEVICES_PER_USER = 20
+	MAX_DEVICE_TOKEN_LENGTH = 4096
+)
+
+type deviceService struct {
+	logger *zap.Logger
+	repo *repository.Repository
+	mobilePush map[string]mobilepush.Provider
+}
+
+func newDeviceService(logger *zap.Logger, repo *repository.Repository, mobilePush map[string]mobilepush.Provider) Device {
+	return &deviceService{
+		logger: logger,
+		repo: repo,
+		mobilePush: mobilePush,
+	}
+}
+
+func (s *deviceService) RegisterDevice(ctx context.Context, userID uuid.UUID, platform string, token string) (int64, error) {
+	if _, ok := s.mobilePush[platform]; !ok {
+		return 0, ErrUnsupportedPlatform
+	}
+	if token == "" || len(token) > MAX_DEVICE_TOKEN_LENGTH {
+		return 0, ErrInvalidDeviceToken
+	}
+
+	devices, err := s.repo.Postgres.Device.GetUserTokens(ctx, userID)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get user(%s)'s device tokens: %s", userID.String(), err.Error())
+		return 0, ErrInternal
+	}
+	if len(devices) >= MAX_DEVICES_PER_USER {
+		return 0, ErrTooManyDevices
+	}
+
+	id, err := s.repo.Postgres.Device.CreateToken(ctx, model.DeviceToken{
+		UserID: userID,
+		Platform: platform,
+		Token: token,
+	})
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to create user(%s)'s device token: %s", userID.String(), err.Error())
+		return 0, ErrInternal
+	}
+
+	return id, nil
+}
+
+func (s *deviceService) GetDevices(ctx context.Context, userID uuid.UUID) ([]*model.DeviceToken, error) {
+	devices, err := s.repo.Postgres.Device.GetUserTokens(ctx, userID)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get user(%s)'s device tokens: %s", userID.String(), err.Error())
+		return nil, ErrInternal
+	}
+
+	return devices, nil
+}
+
+func (s *deviceService) RemoveDevice(ctx context.Context, userID uuid.UUID, id int64) error {
+	if err := s.repo.Postgres.Device.DeleteToken(ctx, userID, id); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrDeviceNotFound
+		}
+
+		s.logger.Sugar().Errorf("failed to delete user(%s)'s device token(%d): %s", userID.String(), id, err.Error())
+		return ErrInternal
+	}
+
+	return nil
+}
+
+func mobilePushTitle(notificationType string) string {
+	switch notificationType {
+	case NEW_POST_NOTIFICATION_TYPE:
+		return "New post"
+	case POST_VALIDATION_STATUS_UPDATE_TYPE:
+		return "Post review"
+	default:
+		return "New notification"
+	}
+}
+
+// mobilePushDelivery sends the notification to every app install of the user, badged with
+// their unread count. Notifications of the same type collapse, so a user coming back online
+// sees the latest one instead of a pile of them, and tokens the provider rejects are pruned.
+func (s *notificationService) mobilePushDelivery(msg model.NotificationDelivery) {
+	if len(s.mobilePush) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), PUSH_SEND_TIMEOUT)
+	defer cancel()
+
+	devices, err := s.repo.Postgres.Device.GetUserTokens(ctx, msg.ReceiverID)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get user(%s)'s device tokens: %s", msg.ReceiverID.String(), err.Error())
+		return
+	}
+	if len(devices) == 0 {
+		return
+	}
+
+	// without the count the badge is left as it is rather than reset
+	data := deliveryPayload(msg)
+	var badge *int
+	unread, err := s.repo.Postgres.Notification.CountUnread(ctx, msg.ReceiverID)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to count user(%s)'s unread notifications: %s", msg.ReceiverID.String(), err.Error())
+	} else {
+		count := int(unread)
+		badge = &count
+		data["unread_count"] = strconv.Itoa(count)
+	}
+
+	for _, device := range devices {
+		provider, ok := s.mobilePush[device.Platform]
+		if !ok {
+			continue
+		}
+
+		err := provider.Send(ctx, mobilepush.Message{
+			Token: device.Token,
+			Title: mobilePushTitle(msg.Type),
+			Body: msg.Content,
+			Data: data,
+			CollapseKey: msg.Type,
+			Badge: badge,
+		})
+		if errors.Is(err, mobilepush.ErrInvalidToken) {
+			if err := s.repo.Postgres.Device.DeleteInvalidToken(ctx, device.Token); err != nil {
+				s.logger.Sugar().Errorf("failed to delete invalid device token(%d): %s", device.ID, err.Error())
+			}
+			continue
+		}
+		if err != nil {
+			s.logger.Sugar().Errorf("failed to push to user(%s)'s %s device(%d): %s", msg.ReceiverID.String(), device.Platform, device.ID, err.Error())
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+	"github.com/go-co-op/gocron/v2"
+)
+
+const (
+	// digests are sent at this hour of the user's local time
+	DIGEST_LOCAL_HOUR = 8
+	DIGEST_JOB_INTERVAL = time.Minute * 15
+	DIGEST_MAX_NOTIFICATIONS = 20
+	// a claim that isn't sent or released within this long is taken over by the next run,
+	// it's shorter than DIGEST_JOB_INTERVAL so a crashed replica's digests go out within the hour
+	DIGEST_CLAIM_LEASE = time.Minute * 5
+)
+
+func (s *notificationService) newDigestJob() {
+	s.scheduler.NewJob(gocron.DurationJob(DIGEST_JOB_INTERVAL), gocron.NewTask(func(ctx context.Context) {
+		recipients, err := s.repo.Postgres.Digest.GetRecipients(ctx, DIGEST_LOCAL_HOUR)
+		if err != nil {
+			s.logger.Sugar().Errorf("failed to get digest recipients: %s", err.Error())
+			return
+		}
+
+		now := time.Now()
+		for _, recipient := range recipients {
+			if err := s.sendDigest(ctx, recipient, now); err != nil {
+				s.logger.Sugar().Errorf("failed to send digest to user(%s): %s", recipient.UserID.String(), err.Error())
+			}
+		}
+	}))
+}
+
+// digestPeriod returns the key identifying the digest period the local time belongs to
+// and the start of the period's notifications. Weekly digests only go out on Mondays.
+func digestPeriod(frequency string, local time.Time) (key string, since time.Time, ok bool) {
+	switch frequency {
+	case model.DIGEST_FREQUENCY_DAILY:
+		return "daily:" + local.Format(time.DateOnly), local.AddDate(0, 0, -1), true
+	case model.DIGEST_FREQUENCY_WEEKLY:
+		if local.Weekday() != time.Monday {
+			return "", time.Time{}, false
+		}
+		year, week := local.ISOWeek()
+		return fmt.Sprintf("weekly:%d-W%02d", year, week), local.AddDate(0, 0, -7), true
+	}
+
+	return "", time.Time{}, false
+}
+
+func (s *notificationService) sendDigest(ctx context.Context, recipient *model.DigestRecipient, now time.Time) error {
+	location, err := time.LoadLocation(recipient.Timezone)
+	if err != nil {
+		return err
+	}
+
+	periodKey, since, ok := digestPeriod(recipient.DigestFrequency, now.In(location))
+	if !ok {
+		return nil
+	}
+
+	claimed, err := s.repo.Postgres.Digest.Claim(ctx, recipient.UserID, periodKey, DIGEST_CLAIM_LEASE)
+	if err != nil || !claimed {
+		return err
+	}
+
+	notifications, total, err := s.repo.Postgres.Notification.GetUnreadNotificationsSince(ctx, recipient.UserID, since, DIGEST_MAX_NOTIFICATIONS)
+	if err != nil {
+		s.releaseDigest(ctx, recipient, periodKey)
+		return err
+	}
+
+	if total > 0 {
+		digest := dto.MQDigest{
+			UserID: recipient.UserID,
+			Email: recipient.Email,
+			Username: recipient.Username,
+			Frequency: recipient.DigestFrequency,
+			TotalUnread: total,
+		}
+		for _, n := range notifications {
+			digest.Notifications = append(digest.Notifications, dto.MQDigestItem{
+				Type: n.Type,
+				Content: n.Content,
+				ResourceID: n.ResourceID,
+				CreatedAt: n.CreatedAt,
+			})
+		}
+
+		digestJSON, err := json.Marshal(digest)
+		if err != nil {
+			s.releaseDigest(ctx, recipient, periodKey)
+			return err
+		}
+
+		if err := s.rabbitmq.PublishToQueue(rabbitmq.DIGEST_MAIL_QUEUE, digestJSON); err != nil {
+			s.releaseDigest(ctx, recipient, periodKey)
+			return err
+		}
+	}
+
+	return s.repo.Postgres.Digest.MarkSent(ctx, recipient.UserID, periodKey, total)
+}
+
+func (s *notificationService) releaseDigest(ctx context.Context, recipient *model.DigestRecipient, periodKey string) {
+	if err := s.repo.Postgres.Digest.Release(ctx, recipient.UserID, periodKey); err != nil {
+		s.logger.Sugar().Errorf("failed to release user(%s)'s digest(%s) claim: %s", recipient.UserID.String(), periodKey, err.Error())
+	}
+}
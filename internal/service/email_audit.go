@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/repository"
+	"go.uber.org/zap"
+)
+
+type emailAuditService struct {
+	logger *zap.Logger
+	repo *repository.Repository
+}
+
+func newEmailAuditService(logger *zap.Logger, repo *repository.Repository) EmailAudit {
+	return &emailAuditService{
+		logger: logger,
+		repo: repo,
+	}
+}
+
+func (s *emailAuditService) Search(ctx context.Context, recipient string, from, to time.Time, limit, offset int) ([]*model.EmailAuditRecord, error) {
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return nil, ErrInvalidTimeRange
+	}
+
+	records, err := s.repo.Postgres.EmailAudit.Search(ctx, recipient, from, to, limit, offset)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to search email audit of(%s): %s", recipient, err.Error())
+		return nil, ErrInternal
+	}
+
+	return records, nil
+}
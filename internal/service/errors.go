@@ -5,4 +5,46 @@ import "errors"
 var (
 	ErrInternal = errors.New("internal server error")
 	ErrInvalidInputForGlobalNotification = errors.New("title and resource_link must not be over 255. and title is required")
+	ErrInvalidAudience = errors.New("audience is invalid: user_ids must not be over 10000, min_followers must not be negative and locale must not be empty or over 35")
+	ErrGlobalNotificationNotFound = errors.New("global notification not found")
+	ErrInvalidStatsInterval = errors.New("interval must be one of: hour, day, week")
+	ErrGlobalNotificationContentTooLong = errors.New("content must not be over 20000")
+	ErrInvalidGlobalNotificationVariant = errors.New("variant must be one of: banner, modal, inline")
+	ErrInvalidGlobalNotificationSeverity = errors.New("severity must be one of: info, warning, critical")
+	ErrGlobalNotificationNotDismissible = errors.New("this global notification can not be dismissed")
+	ErrGlobalNotificationStatusChanged = errors.New("global notification status has been changed by someone else, try again")
+	ErrInvalidGlobalNotificationTransition = errors.New("this action is not allowed in the current global notification status")
+	ErrSelfApproval = errors.New("global notification must be approved by another admin")
+	ErrNotificationNotFound = errors.New("notification not found")
+	ErrInvalidDigestFrequency = errors.New("digest_frequency must be one of: off, daily, weekly")
+	ErrInvalidTimezone = errors.New("timezone must be a valid IANA time zone name")
+	ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
+	ErrUnsubscribeTokenExpired = errors.New("unsubscribe link has expired, change your preferences in the app settings")
+	ErrSuppressionNotFound = errors.New("email suppression not found")
+	ErrInvalidTimeRange = errors.New("from must be before to")
+	ErrEmailTypeNotFound = errors.New("email type not found")
+	ErrInvalidEmailData = errors.New("data does not fit the email type")
+	ErrInvalidEmail = errors.New("invalid email address")
+	ErrEmailSuppressed = errors.New("email address is on the suppression list")
+	ErrPushDisabled = errors.New("web push is not configured")
+	ErrInvalidPushSubscription = errors.New("push subscription must have an https endpoint and valid p256dh and auth keys")
+	ErrTooManyPushSubscriptions = errors.New("too many push subscriptions, remove some first")
+	ErrPushSubscriptionNotFound = errors.New("push subscription not found")
+	ErrUnsupportedPlatform = errors.New("platform must be android or ios and have mobile push configured")
+	ErrInvalidDeviceToken = errors.New("device token must not be empty or over 4096")
+	ErrTooManyDevices = errors.New("too many devices, remove some first")
+	ErrDeviceNotFound = errors.New("device not found")
+	ErrInvalidWebhookURL = errors.New("webhook url must be an absolute https url")
+	ErrInvalidWebhookTypes = errors.New("types must not be empty and be one of: post, post-validation-status-update")
+	ErrTooManyWebhooks = errors.New("too many webhooks, remove some first")
+	ErrWebhookNotFound = errors.New("webhook not found")
+	ErrInvalidSMSCallbackToken = errors.New("invalid sms callback token")
+	ErrInvalidSMSStatus = errors.New("id is required and status must be one of: sent, delivered, undelivered, failed")
+	ErrSMSMessageNotFound = errors.New("sms message not found")
+	ErrInvalidQuietHours = errors.New("quiet_hours_start and quiet_hours_end must both be different HH:MM times or both be empty")
+	ErrInvalidPresenceBatch = errors.New("user_ids must not be empty or over 500")
+	ErrQueueNotFound = errors.New("queue not found")
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+	ErrInvalidDeadLetterID = errors.New("dead letter id is required")
+	ErrInvalidUserID = errors.New("user_id must be a uuid string")
 )
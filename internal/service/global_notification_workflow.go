@@ -0,0 +1,122 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Global notifications go through draft -> pending_approval -> approved -> published.
+// A pending notification can be rejected back to draft. Edits of a published notification
+// go through the same steps as a revision, see UpdateGlobalNotification.
+
+func (s *notificationService) SubmitGlobalNotification(ctx context.Context, id int64, adminID uuid.UUID, comment string) error {
+	_, err := s.transitionGlobalNotification(ctx, id, adminID, comment, model.GLOBAL_NOTIFICATION_ACTION_SUBMITTED, model.GLOBAL_NOTIFICATION_STATUS_DRAFT, model.GLOBAL_NOTIFICATION_STATUS_PENDING_APPROVAL)
+	return err
+}
+
+func (s *notificationService) ApproveGlobalNotification(ctx context.Context, id int64, adminID uuid.UUID, comment string) error {
+	gn, err := s.findGlobalNotification(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// two-person rule: neither the author, the submitter nor the last editor can approve
+	if !canApproveGlobalNotification(gn, adminID) {
+		return ErrSelfApproval
+	}
+
+	// the transition enforces the rule as well, so an edit or a submission since the check can't slip through
+	_, err = s.transitionGlobalNotification(ctx, id, adminID, comment, model.GLOBAL_NOTIFICATION_ACTION_APPROVED, model.GLOBAL_NOTIFICATION_STATUS_PENDING_APPROVAL, model.GLOBAL_NOTIFICATION_STATUS_APPROVED)
+	if err != ErrGlobalNotificationStatusChanged {
+		return err
+	}
+
+	gn, err = s.findGlobalNotification(ctx, id)
+	if err != nil {
+		return err
+	}
+	if gn.Status == model.GLOBAL_NOTIFICATION_STATUS_PENDING_APPROVAL {
+		return ErrSelfApproval
+	}
+
+	return ErrGlobalNotificationStatusChanged
+}
+
+func canApproveGlobalNotification(gn *model.GlobalNotification, adminID uuid.UUID) bool {
+	for _, actor := range []*uuid.UUID{&gn.PosterID, gn.SubmittedBy, gn.UpdatedBy} {
+		if actor != nil && *actor == adminID {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *notificationService) RejectGlobalNotification(ctx context.Context, id int64, adminID uuid.UUID, comment string) error {
+	_, err := s.transitionGlobalNotification(ctx, id, adminID, comment, model.GLOBAL_NOTIFICATION_ACTION_REJECTED, model.GLOBAL_NOTIFICATION_STATUS_PENDING_APPROVAL, model.GLOBAL_NOTIFICATION_STATUS_DRAFT)
+	return err
+}
+
+func (s *notificationService) PublishGlobalNotification(ctx context.Context, id int64, adminID uuid.UUID, comment string) error {
+	gn, err := s.transitionGlobalNotification(ctx, id, adminID, comment, model.GLOBAL_NOTIFICATION_ACTION_PUBLISHED, model.GLOBAL_NOTIFICATION_STATUS_APPROVED, model.GLOBAL_NOTIFICATION_STATUS_PUBLISHED)
+	if err != nil {
+		return err
+	}
+
+	// a revision only replaces the content of a notification users have been sent already,
+	// they see the new content the next time they fetch it
+	if gn.RevisionOf != nil {
+		return nil
+	}
+
+	publishedAt := time.Now()
+	gn.PublishedAt = &publishedAt
+	if err := s.publishGlobalNotificationBroadcast(ctx, *gn); err != nil {
+		s.logger.Sugar().Errorf("failed to publish global notification(%d) broadcast: %s", id, err.Error())
+	}
+
+	return nil
+}
+
+func (s *notificationService) transitionGlobalNotification(ctx context.Context, id int64, adminID uuid.UUID, comment, action, from, to string) (*model.GlobalNotification, error) {
+	gn, err := s.findGlobalNotification(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if gn.Status != from {
+		return nil, ErrInvalidGlobalNotificationTransition
+	}
+
+	if err := s.repo.Postgres.Notification.TransitionGlobalNotification(ctx, model.GlobalNotificationEvent{
+		NotificationID: id,
+		ActorID: adminID,
+		Action: action,
+		FromStatus: from,
+		ToStatus: to,
+		Comment: comment,
+	}); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrGlobalNotificationStatusChanged
+		}
+
+		s.logger.Sugar().Errorf("failed to move global notification(%d) from %s to %s: %s", id, from, to, err.Error())
+		return nil, ErrInternal
+	}
+
+	gn.Status = to
+	return gn, nil
+}
+
+func (s *notificationService) GetGlobalNotificationAuditTrail(ctx context.Context, id int64) ([]*model.GlobalNotificationEvent, error) {
+	events, err := s.repo.Postgres.Notification.GetGlobalNotificationEvents(ctx, id)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get global notification(%d) audit trail: %s", id, err.Error())
+		return nil, ErrInternal
+	}
+
+	return events, nil
+}
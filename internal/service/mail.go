@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+
+	"github.com/BloggingApp/notification-service/internal/mailer"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type mailService struct {
+	logger *zap.Logger
+	mailer *mailer.Mailer
+}
+
+func newMailService(logger *zap.Logger, mailer *mailer.Mailer) Mail {
+	return &mailService{
+		logger: logger,
+		mailer: mailer,
+	}
+}
+
+func (s *mailService) ListEmailTypes() []string {
+	return mailer.EmailTypes()
+}
+
+func (s *mailService) PreviewEmail(ctx context.Context, typeName string, data json.RawMessage) ([]*mailer.Preview, error) {
+	previews, err := s.mailer.Preview(typeName, data)
+	if err != nil {
+		return nil, s.mailerError(typeName, err)
+	}
+
+	return previews, nil
+}
+
+func (s *mailService) SendTestEmail(ctx context.Context, adminID uuid.UUID, typeName string, to string, data json.RawMessage) ([]string, error) {
+	address, err := mail.ParseAddress(to)
+	if err != nil {
+		return nil, ErrInvalidEmail
+	}
+
+	messageIDs, err := s.mailer.SendTest(typeName, address.Address, data)
+	if err != nil {
+		return nil, s.mailerError(typeName, err)
+	}
+
+	s.logger.Sugar().Infof("admin(%s) sent test %s mail to(%s)", adminID.String(), typeName, address.Address)
+
+	return messageIDs, nil
+}
+
+func (s *mailService) mailerError(typeName string, err error) error {
+	switch {
+	case errors.Is(err, mailer.ErrUnknownEmailType):
+		return ErrEmailTypeNotFound
+	case errors.Is(err, mailer.ErrInvalidEmailData):
+		return fmt.Errorf("%w (%s)", ErrInvalidEmailData, err.Error())
+	case errors.Is(err, mailer.ErrRecipientSuppressed):
+		return ErrEmailSuppressed
+	}
+
+	s.logger.Sugar().Errorf("failed to render or send %s mail: %s", typeName, err.Error())
+	return ErrInternal
+}
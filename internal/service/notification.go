@@ -9,10 +9,13 @@ import (
 	"time"
 
 	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/markdown"
+	"github.com/BloggingApp/notification-service/internal/mobilepush"
 	"github.com/BloggingApp/notification-service/internal/model"
 	"github.com/BloggingApp/notification-service/internal/rabbitmq"
 	"github.com/BloggingApp/notification-service/internal/repository"
 	"github.com/BloggingApp/notification-service/internal/repository/redisrepo"
+	"github.com/BloggingApp/notification-service/internal/webpush"
 	"github.com/go-co-op/gocron/v2"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
@@ -28,10 +31,13 @@ type notificationService struct {
 	rabbitmq *rabbitmq.MQConn
 	scheduler gocron.Scheduler
 	conns *sync.Map
-	deliveryChan chan model.NotificationDelivery
+	deliveryChan chan []model.NotificationDelivery // batches of at most ROUTING_BATCH_SIZE
+	webPush *webpush.Client
+	mobilePush map[string]mobilepush.Provider
+	replicaID string // tells this replica's sockets apart from other replicas' in presence
 }
 
-func newNotificationService(logger *zap.Logger, repo *repository.Repository, rdb *redis.Client, rabbitmq *rabbitmq.MQConn) Notification {
+func newNotificationService(logger *zap.Logger, repo *repository.Repository, rdb *redis.Client, rabbitmq *rabbitmq.MQConn, webPush *webpush.Client, mobilePush map[string]mobilepush.Provider) Notification {
 	scheduler, err := gocron.NewScheduler()
 	if err != nil {
 		panic(err)
@@ -44,10 +50,13 @@ func newNotificationService(logger *zap.Logger, repo *repository.Repository, rdb
 		rabbitmq: rabbitmq,
 		scheduler: scheduler,
 		conns: &sync.Map{},
-		deliveryChan: make(chan model.NotificationDelivery, 1000),
+		deliveryChan: make(chan []model.NotificationDelivery, 100),
+		webPush: webPush,
+		mobilePush: mobilePush,
+		replicaID: uuid.NewString(),
 	}
 
-	for range 5 {
+	for range ROUTING_WORKERS {
 		go s.deliveryWorker()
 	}
 
@@ -55,48 +64,53 @@ func newNotificationService(logger *zap.Logger, repo *repository.Repository, rdb
 }
 
 func (s *notificationService) deliveryWorker() {
-	for msg := range s.deliveryChan {
-		val, ok := s.conns.Load(msg.ReceiverID)
-		if !ok {
-			continue
-		}
-
-		conn, ok := val.(*websocket.Conn)
-		if !ok {
-			continue
-		}
+	for msgs := range s.deliveryChan {
+		// webhooks get every notification, wherever it's routed
+		s.webhookDelivery(msgs)
+		s.route(msgs)
+	}
+}
 
-		payload := map[string]string{
-			"type": msg.Type,
-			"content": msg.Content,
-			"resource_id": msg.ResourceID,
-		}
-		if err := conn.WriteJSON(payload); err != nil {
-			s.logger.Sugar().Errorf("failed to write json msg to receiver(%s)'s conn: %s", msg.ReceiverID.String(), err.Error())
-		}
+func deliveryPayload(msg model.NotificationDelivery) map[string]string {
+	return map[string]string{
+		"type": msg.Type,
+		"content": msg.Content,
+		"resource_id": msg.ResourceID,
 	}
 }
 
 func (s *notificationService) RegisterConnection(userID uuid.UUID, conn *websocket.Conn) {
-	s.conns.Store(userID, conn)
+	c := newWSConn(conn)
+	if prev, loaded := s.conns.Swap(userID, c); loaded {
+		if prevConn, ok := prev.(*wsConn); ok {
+			prevConn.close()
+		}
+	}
+	s.setPresence(userID, c.presenceState())
 
-	go func(userID uuid.UUID, c *websocket.Conn) {
+	go func(userID uuid.UUID, c *wsConn) {
 		for {
-			_, _, err := c.ReadMessage()
+			_, data, err := c.conn.ReadMessage()
 			if err != nil {
-				s.UnregisterConnection(userID)
+				c.close()
+				// the user may have already reconnected, so only remove this exact connection
+				if s.conns.CompareAndDelete(userID, c) {
+					s.setPresence(userID, "")
+				}
 				break
 			}
+
+			s.handleClientMessage(userID, c, data)
 		}
-	}(userID, conn)
+	}(userID, c)
 }
 
 func (s *notificationService) UnregisterConnection(userID uuid.UUID) {
-	if val, ok := s.conns.Load(userID); ok {
-		if conn, ok := val.(*websocket.Conn); ok {
-			conn.Close()
+	if val, ok := s.conns.LoadAndDelete(userID); ok {
+		if conn, ok := val.(*wsConn); ok {
+			conn.close()
 		}
-		s.conns.Delete(userID)
+		s.setPresence(userID, "")
 	}
 }
 
@@ -110,21 +124,21 @@ func (s *notificationService) StartProcessingNewPostNotifications(ctx context.Co
 		var postCreatedDto dto.MQPostCreated
 		if err := json.Unmarshal(msg.Body, &postCreatedDto); err != nil {
 			s.logger.Sugar().Errorf("failed to unmarshal data from queue(%s) to json: %s", rabbitmq.NEW_POST_QUEUE, err.Error())
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.NEW_POST_QUEUE, msg, err)
 			continue
 		}
 
 		receivers, err := s.repo.Postgres.Notification.GetInterestedFollowers(ctx, postCreatedDto.UserID)
 		if err != nil {
 			s.logger.Sugar().Errorf("failed to get user(%s)'s interested followers: %s", postCreatedDto.UserID.String(), err.Error())
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.NEW_POST_QUEUE, msg, err)
 			continue
 		}
 
 		author, err := s.repo.Postgres.User.FindByID(ctx, postCreatedDto.UserID)
 		if err != nil {
 			s.logger.Sugar().Errorf("failed to get post(%d) author(%s) from postgres: %s", postCreatedDto.PostID, postCreatedDto.UserID.String(), err.Error())
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.NEW_POST_QUEUE, msg, err)
 			continue
 		}
 
@@ -143,25 +157,27 @@ func (s *notificationService) StartProcessingNewPostNotifications(ctx context.Co
 
 		if err := s.repo.Postgres.Notification.CreateBatched(ctx, notifications, 1000); err != nil {
 			s.logger.Sugar().Errorf("failed to create batched notifications for post(%d): %s", postCreatedDto.PostID, err.Error())
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.NEW_POST_QUEUE, msg, err)
 			continue
 		}
 
 		msg.Ack(false)
 
+		deliveries := make([]model.NotificationDelivery, 0, len(receivers))
 		for _, receiver := range receivers {
-			s.deliveryChan <- model.NotificationDelivery{
+			deliveries = append(deliveries, model.NotificationDelivery{
 				ReceiverID: receiver,
 				Type: NEW_POST_NOTIFICATION_TYPE,
 				Content: content,
 				ResourceID: resourceID,
-			}
+			})
 		}
+		s.deliver(deliveries...)
 	}
 }
 
 func (s *notificationService) GetUserNotifications(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.Notification, error) {
-	notificationsCache, err := redisrepo.Get[[]*model.Notification](s.rdb, ctx, redisrepo.UserNotificationsKey(userID.String(), limit, offset))
+	notificationsCache, err := redisrepo.HGet[[]*model.Notification](s.rdb, ctx, redisrepo.UserNotificationsKey(userID.String()), redisrepo.UserNotificationsPageField(limit, offset))
 	if err == nil {
 		return *notificationsCache, nil
 	}
@@ -176,13 +192,31 @@ func (s *notificationService) GetUserNotifications(ctx context.Context, userID u
 		return nil, ErrInternal
 	}
 
-	if err := redisrepo.SetJSON(s.rdb, ctx, redisrepo.UserNotificationsKey(userID.String(), limit, offset), notifications, time.Minute * 2); err != nil {
+	if err := redisrepo.HSetJSON(s.rdb, ctx, redisrepo.UserNotificationsKey(userID.String()), redisrepo.UserNotificationsPageField(limit, offset), notifications, time.Minute * 2); err != nil {
 		s.logger.Sugar().Errorf("failed to set user(%s)'s notification in redis cache: %s", userID.String(), err.Error())
 	}
 
 	return notifications, nil
 }
 
+func (s *notificationService) MarkAsRead(ctx context.Context, userID uuid.UUID, notificationID int64) error {
+	if err := s.repo.Postgres.Notification.MarkAsRead(ctx, userID, notificationID); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotificationNotFound
+		}
+
+		s.logger.Sugar().Errorf("failed to mark user(%s)'s notification(%d) as read: %s", userID.String(), notificationID, err.Error())
+		return ErrInternal
+	}
+
+	// every cached page may hold the notification
+	if err := redisrepo.Del(s.rdb, ctx, redisrepo.UserNotificationsKey(userID.String())); err != nil {
+		s.logger.Sugar().Errorf("failed to delete user(%s)'s cached notifications from redis: %s", userID.String(), err.Error())
+	}
+
+	return nil
+}
+
 func (s *notificationService) newDeleteOldNotificationsJob() {
 	s.scheduler.NewJob(gocron.DurationJob(time.Hour * 12), gocron.NewTask(func(ctx context.Context) {
 		if err := s.repo.Postgres.Notification.DeleteOldNotifications(ctx); err != nil {
@@ -191,18 +225,122 @@ func (s *notificationService) newDeleteOldNotificationsJob() {
 	}))
 }
 
+func (s *notificationService) newDeleteOldEmailAuditJob() {
+	s.scheduler.NewJob(gocron.DurationJob(time.Hour * 12), gocron.NewTask(func(ctx context.Context) {
+		if err := s.repo.Postgres.EmailAudit.DeleteOldRecords(ctx); err != nil {
+			s.logger.Sugar().Errorf("failed to delete old email audit records: %s", err.Error())
+		}
+	}))
+}
+
+func (s *notificationService) newDeleteOldWebhookDeliveriesJob() {
+	s.scheduler.NewJob(gocron.DurationJob(time.Hour * 12), gocron.NewTask(func(ctx context.Context) {
+		if err := s.repo.Postgres.Webhook.DeleteOldDeliveries(ctx); err != nil {
+			s.logger.Sugar().Errorf("failed to delete old webhook deliveries: %s", err.Error())
+		}
+	}))
+}
+
 func (s *notificationService) StartJobs() {
 	s.newDeleteOldNotificationsJob()
+	s.newDeleteOldEmailAuditJob()
+	s.newDeleteOldWebhookDeliveriesJob()
+	s.newDeleteOldRoutingDecisionsJob()
+	s.newDigestJob()
 
 	s.scheduler.Start()
 }
 
-func (s *notificationService) CreateGlobalNotification(ctx context.Context, gn model.GlobalNotification) error {
+func (s *notificationService) CreateGlobalNotification(ctx context.Context, gn model.GlobalNotification) (int64, error) {
+	if gn.Variant == "" {
+		gn.Variant = GLOBAL_NOTIFICATION_VARIANT_INLINE
+	}
+	if gn.Severity == "" {
+		gn.Severity = GLOBAL_NOTIFICATION_SEVERITY_INFO
+	}
+
+	if err := s.prepareGlobalNotification(&gn); err != nil {
+		return 0, err
+	}
+
+	if err := validateGlobalNotificationAudience(gn.Audience); err != nil {
+		return 0, err
+	}
+	if gn.Audience.IsEveryone() {
+		gn.Audience = nil
+	}
+
+	id, err := s.repo.Postgres.Notification.CreateGlobalNotification(ctx, gn)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to create global notification by admin(%s): %s", gn.PosterID.String(), err.Error())
+		return 0, ErrInternal
+	}
+
+	return id, nil
+}
+
+func (s *notificationService) CountGlobalNotificationAudience(ctx context.Context, audience *model.GlobalNotificationAudience) (int64, error) {
+	if err := validateGlobalNotificationAudience(audience); err != nil {
+		return 0, err
+	}
+
+	count, err := s.repo.Postgres.Notification.CountGlobalNotificationAudience(ctx, audience)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to count global notification audience: %s", err.Error())
+		return 0, ErrInternal
+	}
+
+	return count, nil
+}
+
+// prepareGlobalNotification validates the notification and renders its Markdown content to sanitized HTML
+func (s *notificationService) prepareGlobalNotification(gn *model.GlobalNotification) error {
 	if len(gn.Title) > 255 || gn.Title == "" || len(gn.ResourceLink) > 255 {
 		return ErrInvalidInputForGlobalNotification
 	}
 
-	return s.repo.Postgres.Notification.CreateGlobalNotification(ctx, gn)
+	if len(gn.Content) > MAX_GLOBAL_NOTIFICATION_CONTENT_LENGTH {
+		return ErrGlobalNotificationContentTooLong
+	}
+
+	switch gn.Variant {
+	case GLOBAL_NOTIFICATION_VARIANT_BANNER, GLOBAL_NOTIFICATION_VARIANT_MODAL, GLOBAL_NOTIFICATION_VARIANT_INLINE:
+	default:
+		return ErrInvalidGlobalNotificationVariant
+	}
+
+	switch gn.Severity {
+	case GLOBAL_NOTIFICATION_SEVERITY_INFO, GLOBAL_NOTIFICATION_SEVERITY_WARNING, GLOBAL_NOTIFICATION_SEVERITY_CRITICAL:
+	default:
+		return ErrInvalidGlobalNotificationSeverity
+	}
+
+	contentHTML, err := markdown.ToSafeHTML(gn.Content)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to render global notification content: %s", err.Error())
+		return ErrInternal
+	}
+	gn.ContentHTML = contentHTML
+
+	return nil
+}
+
+func validateGlobalNotificationAudience(audience *model.GlobalNotificationAudience) error {
+	if audience == nil {
+		return nil
+	}
+
+	if len(audience.UserIDs) > MAX_AUDIENCE_USER_IDS {
+		return ErrInvalidAudience
+	}
+	if audience.MinFollowers != nil && *audience.MinFollowers < 0 {
+		return ErrInvalidAudience
+	}
+	if audience.Locale != nil && (*audience.Locale == "" || len(*audience.Locale) > MAX_LOCALE_LENGTH) {
+		return ErrInvalidAudience
+	}
+
+	return nil
 }
 
 func (s *notificationService) GetGlobalNotifications(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.GlobalNotification, error) {
@@ -210,6 +348,19 @@ func (s *notificationService) GetGlobalNotifications(ctx context.Context, userID
 }
 
 func (s *notificationService) MarkGlobalNotificationAsRead(ctx context.Context, userID uuid.UUID, notificationID int64) error {
+	gn, err := s.findGlobalNotification(ctx, notificationID)
+	if err != nil {
+		return err
+	}
+
+	if gn.Status != model.GLOBAL_NOTIFICATION_STATUS_PUBLISHED {
+		return ErrGlobalNotificationNotFound
+	}
+
+	if !gn.Dismissible {
+		return ErrGlobalNotificationNotDismissible
+	}
+
 	return s.repo.Postgres.Notification.MarkGlobalNotificationAsRead(ctx, userID, notificationID)
 }
 
@@ -223,7 +374,7 @@ func (s *notificationService) StartProcessingPostValidationStatusUpdates(ctx con
 		var data dto.MQPostValidationStatusUpdate
 		if err := json.Unmarshal(msg.Body, &data); err != nil {
 			s.logger.Sugar().Errorf("failed to unmarshal data from queue(%s) to json: %s", rabbitmq.POST_VALIDATION_STATUS_UPDATES_QUEUE, err.Error())
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.POST_VALIDATION_STATUS_UPDATES_QUEUE, msg, err)
 			continue
 		}
 
@@ -236,25 +387,191 @@ func (s *notificationService) StartProcessingPostValidationStatusUpdates(ctx con
 			ResourceID: resourceID,
 		}); err != nil {
 			s.logger.Sugar().Errorf("failed to create post validation status update notification for user(%s): %s", data.UserID.String(), err.Error())
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.POST_VALIDATION_STATUS_UPDATES_QUEUE, msg, err)
 			continue
 		}
 
 		msg.Ack(false)
 
-		receiver, ok := s.conns.Load(data.UserID)
-		if !ok {
-			continue
-		}
-		receiverID, ok := receiver.(uuid.UUID)
-		if !ok {
-			continue
-		}
-		s.deliveryChan <- model.NotificationDelivery{
-			ReceiverID: receiverID,
+		s.deliver(model.NotificationDelivery{
+			ReceiverID: data.UserID,
 			Type: POST_VALIDATION_STATUS_UPDATE_TYPE,
 			Content: data.StatusMsg,
 			ResourceID: resourceID,
+		})
+	}
+}
+
+func (s *notificationService) ListGlobalNotifications(ctx context.Context, status string, limit, offset int) ([]*model.GlobalNotification, error) {
+	notifications, err := s.repo.Postgres.Notification.ListGlobalNotifications(ctx, status, limit, offset)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to list global notifications: %s", err.Error())
+		return nil, ErrInternal
+	}
+
+	return notifications, nil
+}
+
+func (s *notificationService) findGlobalNotification(ctx context.Context, id int64) (*model.GlobalNotification, error) {
+	gn, err := s.repo.Postgres.Notification.FindGlobalNotificationByID(ctx, id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrGlobalNotificationNotFound
+		}
+
+		s.logger.Sugar().Errorf("failed to find global notification(%d): %s", id, err.Error())
+		return nil, ErrInternal
+	}
+
+	return gn, nil
+}
+
+// UpdateGlobalNotification edits the notification and sends it back to draft, so it has to be approved again.
+// Edits of a published notification are staged in a revision, which goes through the same approval and
+// replaces the live content only once it's published. Further edits of the published notification change that revision.
+func (s *notificationService) UpdateGlobalNotification(ctx context.Context, id int64, editorID uuid.UUID, input dto.UpdateGlobalNotification) (*model.GlobalNotification, error) {
+	gn, err := s.findGlobalNotification(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if gn.Status == model.GLOBAL_NOTIFICATION_STATUS_PUBLISHED {
+		revision, err := s.repo.Postgres.Notification.FindGlobalNotificationRevision(ctx, id)
+		if err == pgx.ErrNoRows {
+			return s.createGlobalNotificationRevision(ctx, *gn, editorID, input)
+		}
+		if err != nil {
+			s.logger.Sugar().Errorf("failed to find revision of global notification(%d): %s", id, err.Error())
+			return nil, ErrInternal
+		}
+
+		gn = revision
+	}
+
+	applyGlobalNotificationUpdate(gn, input)
+
+	if err := s.prepareGlobalNotification(gn); err != nil {
+		return nil, err
+	}
+
+	event := model.GlobalNotificationEvent{
+		NotificationID: gn.ID,
+		ActorID: editorID,
+		Action: model.GLOBAL_NOTIFICATION_ACTION_UPDATED,
+		FromStatus: gn.Status,
+		ToStatus: model.GLOBAL_NOTIFICATION_STATUS_DRAFT,
+	}
+
+	if err := s.repo.Postgres.Notification.UpdateGlobalNotification(ctx, *gn, event); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrGlobalNotificationStatusChanged
+		}
+
+		s.logger.Sugar().Errorf("failed to update global notification(%d): %s", gn.ID, err.Error())
+		return nil, ErrInternal
+	}
+
+	gn.Status = event.ToStatus
+	gn.SubmittedBy = nil
+	gn.ApprovedBy = nil
+	gn.UpdatedBy = &editorID
+
+	return gn, nil
+}
+
+// createGlobalNotificationRevision stages the edit of the published notification as a draft revision by the editor
+func (s *notificationService) createGlobalNotificationRevision(ctx context.Context, gn model.GlobalNotification, editorID uuid.UUID, input dto.UpdateGlobalNotification) (*model.GlobalNotification, error) {
+	revision := model.GlobalNotification{
+		PosterID: editorID,
+		Title: gn.Title,
+		Content: gn.Content,
+		ResourceLink: gn.ResourceLink,
+		Variant: gn.Variant,
+		Severity: gn.Severity,
+		Dismissible: gn.Dismissible,
+		Status: model.GLOBAL_NOTIFICATION_STATUS_DRAFT,
+		RevisionOf: &gn.ID,
+	}
+	applyGlobalNotificationUpdate(&revision, input)
+
+	if err := s.prepareGlobalNotification(&revision); err != nil {
+		return nil, err
+	}
+
+	id, err := s.repo.Postgres.Notification.CreateGlobalNotification(ctx, revision)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to create revision of global notification(%d) by admin(%s): %s", gn.ID, editorID.String(), err.Error())
+		return nil, ErrInternal
+	}
+
+	revision.ID = id
+	revision.CreatedAt = time.Now()
+
+	return &revision, nil
+}
+
+func applyGlobalNotificationUpdate(gn *model.GlobalNotification, input dto.UpdateGlobalNotification) {
+	if input.Title != nil {
+		gn.Title = *input.Title
+	}
+	if input.Content != nil {
+		gn.Content = *input.Content
+	}
+	if input.ResourceLink != nil {
+		gn.ResourceLink = *input.ResourceLink
+	}
+	if input.Variant != nil {
+		gn.Variant = *input.Variant
+	}
+	if input.Severity != nil {
+		gn.Severity = *input.Severity
+	}
+	if input.Dismissible != nil {
+		gn.Dismissible = *input.Dismissible
+	}
+}
+
+func (s *notificationService) DeleteGlobalNotification(ctx context.Context, id int64, adminID uuid.UUID) error {
+	gn, err := s.findGlobalNotification(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Postgres.Notification.DeleteGlobalNotification(ctx, model.GlobalNotificationEvent{
+		NotificationID: id,
+		ActorID: adminID,
+		Action: model.GLOBAL_NOTIFICATION_ACTION_DELETED,
+		FromStatus: gn.Status,
+	}); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrGlobalNotificationNotFound
 		}
+
+		s.logger.Sugar().Errorf("failed to delete global notification(%d): %s", id, err.Error())
+		return ErrInternal
+	}
+
+	return nil
+}
+
+func (s *notificationService) GetGlobalNotificationStats(ctx context.Context, id int64, interval string) (*model.GlobalNotificationStats, error) {
+	if interval == "" {
+		interval = STATS_INTERVAL_DAY
 	}
+	if interval != STATS_INTERVAL_HOUR && interval != STATS_INTERVAL_DAY && interval != STATS_INTERVAL_WEEK {
+		return nil, ErrInvalidStatsInterval
+	}
+
+	gn, err := s.findGlobalNotification(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.repo.Postgres.Notification.GetGlobalNotificationStats(ctx, *gn, interval)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get global notification(%d) stats: %s", id, err.Error())
+		return nil, ErrInternal
+	}
+
+	return stats, nil
 }
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/repository"
+	"github.com/BloggingApp/notification-service/internal/routing"
+	"github.com/BloggingApp/notification-service/internal/unsubscribe"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type preferencesService struct {
+	logger *zap.Logger
+	repo *repository.Repository
+	unsubscribeSigner *unsubscribe.Signer
+}
+
+func newPreferencesService(logger *zap.Logger, repo *repository.Repository, unsubscribeSigner *unsubscribe.Signer) Preferences {
+	return &preferencesService{
+		logger: logger,
+		repo: repo,
+		unsubscribeSigner: unsubscribeSigner,
+	}
+}
+
+func (s *preferencesService) Get(ctx context.Context, userID uuid.UUID) (*model.NotificationPreferences, error) {
+	prefs, err := s.repo.Postgres.Preferences.Get(ctx, userID)
+	if err == pgx.ErrNoRows {
+		return model.DefaultNotificationPreferences(userID), nil
+	}
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get user(%s)'s notification preferences: %s", userID.String(), err.Error())
+		return nil, ErrInternal
+	}
+
+	return prefs, nil
+}
+
+func (s *preferencesService) Update(ctx context.Context, userID uuid.UUID, input dto.UpdateNotificationPreferences) (*model.NotificationPreferences, error) {
+	prefs, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.DigestFrequency != nil {
+		prefs.DigestFrequency = *input.DigestFrequency
+	}
+	if input.Timezone != nil {
+		prefs.Timezone = *input.Timezone
+	}
+	if input.PushEnabled != nil {
+		prefs.PushEnabled = *input.PushEnabled
+	}
+	if input.QuietHoursStart != nil {
+		prefs.QuietHoursStart = input.QuietHoursStart
+	}
+	if input.QuietHoursEnd != nil {
+		prefs.QuietHoursEnd = input.QuietHoursEnd
+	}
+
+	switch prefs.DigestFrequency {
+	case model.DIGEST_FREQUENCY_OFF, model.DIGEST_FREQUENCY_DAILY, model.DIGEST_FREQUENCY_WEEKLY:
+	default:
+		return nil, ErrInvalidDigestFrequency
+	}
+
+	if _, err := time.LoadLocation(prefs.Timezone); err != nil || prefs.Timezone == "" || prefs.Timezone == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+
+	if err := normalizeQuietHours(prefs); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Postgres.Preferences.Upsert(ctx, *prefs); err != nil {
+		s.logger.Sugar().Errorf("failed to update user(%s)'s notification preferences: %s", userID.String(), err.Error())
+		return nil, ErrInternal
+	}
+
+	prefs.UpdatedAt = time.Now()
+	return prefs, nil
+}
+
+// normalizeQuietHours checks that quiet hours have both a start and an end, and stores removed ones as nil
+func normalizeQuietHours(prefs *model.NotificationPreferences) error {
+	if prefs.QuietHoursStart != nil && *prefs.QuietHoursStart == "" {
+		prefs.QuietHoursStart = nil
+	}
+	if prefs.QuietHoursEnd != nil && *prefs.QuietHoursEnd == "" {
+		prefs.QuietHoursEnd = nil
+	}
+
+	if prefs.QuietHoursStart == nil && prefs.QuietHoursEnd == nil {
+		return nil
+	}
+	if prefs.QuietHoursStart == nil || prefs.QuietHoursEnd == nil {
+		return ErrInvalidQuietHours
+	}
+
+	start, err0 := time.Parse(routing.QUIET_HOURS_LAYOUT, *prefs.QuietHoursStart)
+	end, err1 := time.Parse(routing.QUIET_HOURS_LAYOUT, *prefs.QuietHoursEnd)
+	if err0 != nil || err1 != nil || start.Equal(end) {
+		return ErrInvalidQuietHours
+	}
+
+	return nil
+}
+
+func (s *preferencesService) CheckUnsubscribeToken(token string) (*unsubscribe.Claims, error) {
+	claims, err := s.unsubscribeSigner.Verify(token)
+	if err == unsubscribe.ErrTokenExpired {
+		return nil, ErrUnsubscribeTokenExpired
+	}
+	if err != nil {
+		return nil, ErrInvalidUnsubscribeToken
+	}
+
+	return claims, nil
+}
+
+// Unsubscribe turns off the email category the token was issued for. Unsubscribing twice is not an error.
+func (s *preferencesService) Unsubscribe(ctx context.Context, token string) (*unsubscribe.Claims, error) {
+	claims, err := s.CheckUnsubscribeToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs, err := s.Get(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch claims.Category {
+	case model.EMAIL_CATEGORY_DIGEST:
+		prefs.DigestFrequency = model.DIGEST_FREQUENCY_OFF
+	default:
+		return nil, ErrInvalidUnsubscribeToken
+	}
+
+	if err := s.repo.Postgres.Preferences.Upsert(ctx, *prefs); err != nil {
+		s.logger.Sugar().Errorf("failed to unsubscribe user(%s) from %s emails: %s", claims.UserID.String(), claims.Category, err.Error())
+		return nil, ErrInternal
+	}
+
+	s.logger.Sugar().Infof("user(%s) unsubscribed from %s emails", claims.UserID.String(), claims.Category)
+
+	return claims, nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+	"github.com/BloggingApp/notification-service/internal/repository/redisrepo"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	PRESENCE_HEARTBEAT_INTERVAL = time.Second * 30
+	PRESENCE_TTL = PRESENCE_HEARTBEAT_INTERVAL * 3 // a replica missing three heartbeats is considered gone
+	LAST_SEEN_TTL = time.Hour * 24 * 30
+	PRESENCE_TIMEOUT = time.Second * 5
+	MAX_PRESENCE_BATCH = 500
+	PRESENCE_HEARTBEAT_BATCH = 500 // users refreshed per pipeline
+	PRESENCE_SWEEP_BATCH = 500
+
+	PRESENCE_CLIENT_MESSAGE_TYPE = "presence"
+)
+
+// presenceClientMessage is what clients send over the socket when the user becomes idle or active again
+type presenceClientMessage struct {
+	Type  string `json:"type"`
+	State string `json:"state"`
+}
+
+type presenceService struct {
+	logger *zap.Logger
+	rdb *redis.Client
+}
+
+func newPresenceService(logger *zap.Logger, rdb *redis.Client) Presence {
+	return &presenceService{
+		logger: logger,
+		rdb: rdb,
+	}
+}
+
+func (s *presenceService) GetPresence(ctx context.Context, userID uuid.UUID) (*model.Presence, error) {
+	presences, err := s.GetPresences(ctx, []uuid.UUID{userID})
+	if err != nil {
+		return nil, err
+	}
+
+	return presences[0], nil
+}
+
+func (s *presenceService) GetPresences(ctx context.Context, userIDs []uuid.UUID) ([]*model.Presence, error) {
+	if len(userIDs) == 0 || len(userIDs) > MAX_PRESENCE_BATCH {
+		return nil, ErrInvalidPresenceBatch
+	}
+
+	ids := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = userID.String()
+	}
+
+	states, lastSeen, err := redisrepo.GetPresence(s.rdb, ctx, ids)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get presence of %d user(s) from redis: %s", len(userIDs), err.Error())
+		return nil, ErrInternal
+	}
+
+	presences := make([]*model.Presence, len(userIDs))
+	for i, userID := range userIDs {
+		presences[i] = &model.Presence{
+			UserID: userID,
+			State: states[i],
+			LastSeen: lastSeen[i],
+		}
+	}
+
+	return presences, nil
+}
+
+// setPresence records this replica's state of the user, an empty state meaning the user's socket here is gone,
+// and publishes an event when the user's overall state changes
+func (s *notificationService) setPresence(userID uuid.UUID, state string) {
+	ctx, cancel := context.WithTimeout(context.Background(), PRESENCE_TIMEOUT)
+	defer cancel()
+
+	prev, cur, err := redisrepo.SetPresence(s.rdb, ctx, userID.String(), s.replicaID, state, PRESENCE_TTL, LAST_SEEN_TTL)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to set user(%s)'s presence in redis: %s", userID.String(), err.Error())
+		return
+	}
+	if prev == cur {
+		return
+	}
+
+	s.publishPresenceChange(userID, prev, cur)
+}
+
+func (s *notificationService) publishPresenceChange(userID uuid.UUID, prev string, cur string) {
+	eventJSON, err := json.Marshal(dto.MQPresenceChanged{
+		UserID: userID,
+		State: cur,
+		PreviousState: prev,
+		ChangedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to marshal presence change: %s", err.Error())
+		return
+	}
+
+	if err := s.rabbitmq.PublishExchange(rabbitmq.PRESENCE_EXCHANGE, eventJSON); err != nil {
+		s.logger.Sugar().Errorf("failed to publish user(%s)'s presence change: %s", userID.String(), err.Error())
+	}
+}
+
+// handleClientMessage applies the presence the client reports for the user
+func (s *notificationService) handleClientMessage(userID uuid.UUID, c *wsConn, data []byte) {
+	var msg presenceClientMessage
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Type != PRESENCE_CLIENT_MESSAGE_TYPE {
+		return
+	}
+
+	switch msg.State {
+	case model.PRESENCE_IDLE:
+		c.idle.Store(true)
+	case model.PRESENCE_ONLINE:
+		c.idle.Store(false)
+	default:
+		return
+	}
+
+	s.setPresence(userID, c.presenceState())
+}
+
+// StartPresenceHeartbeats keeps the presence of the users connected to this replica from expiring,
+// and sweeps the presence of users whose replica stopped refreshing it
+func (s *notificationService) StartPresenceHeartbeats(ctx context.Context) {
+	if err := s.rabbitmq.DeclareFanoutExchange(rabbitmq.PRESENCE_EXCHANGE); err != nil {
+		panic(err)
+	}
+
+	ticker := time.NewTicker(PRESENCE_HEARTBEAT_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.heartbeatPresence(ctx)
+			s.sweepPresence(ctx)
+		}
+	}
+}
+
+// heartbeatPresence refreshes the presence of every user connected to this replica, a pipeline per batch
+func (s *notificationService) heartbeatPresence(ctx context.Context) {
+	states := make(map[string]string, PRESENCE_HEARTBEAT_BATCH)
+	flush := func() {
+		ctx, cancel := context.WithTimeout(ctx, PRESENCE_TIMEOUT)
+		defer cancel()
+
+		changes, err := redisrepo.SetPresences(s.rdb, ctx, s.replicaID, states, PRESENCE_TTL, LAST_SEEN_TTL)
+		if err != nil {
+			s.logger.Sugar().Errorf("failed to refresh the presence of %d user(s) in redis: %s", len(states), err.Error())
+		}
+		s.publishPresenceChanges(changes)
+		clear(states)
+	}
+
+	s.conns.Range(func(key, value any) bool {
+		userID, ok := key.(uuid.UUID)
+		if !ok {
+			return true
+		}
+		conn, ok := value.(*wsConn)
+		if !ok {
+			return true
+		}
+
+		states[userID.String()] = conn.presenceState()
+		if len(states) >= PRESENCE_HEARTBEAT_BATCH {
+			flush()
+		}
+		return true
+	})
+	if len(states) > 0 {
+		flush()
+	}
+}
+
+// sweepPresence reports the users of crashed replicas going idle or offline once their fields expire
+func (s *notificationService) sweepPresence(ctx context.Context) {
+	for {
+		sweepCtx, cancel := context.WithTimeout(ctx, PRESENCE_TIMEOUT)
+		changes, err := redisrepo.SweepPresence(s.rdb, sweepCtx, PRESENCE_SWEEP_BATCH, PRESENCE_TTL)
+		cancel()
+		if err != nil {
+			s.logger.Sugar().Errorf("failed to sweep expired presence in redis: %s", err.Error())
+		}
+		s.publishPresenceChanges(changes)
+
+		// a full batch of changes means more users may be waiting
+		if err != nil || len(changes) < PRESENCE_SWEEP_BATCH {
+			return
+		}
+	}
+}
+
+func (s *notificationService) publishPresenceChanges(changes []redisrepo.PresenceChange) {
+	for _, change := range changes {
+		userID, err := uuid.Parse(change.UserID)
+		if err != nil {
+			continue
+		}
+
+		s.publishPresenceChange(userID, change.Previous, change.Current)
+	}
+}
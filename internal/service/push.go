@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+	"github.com/BloggingApp/notification-service/internal/repository"
+	"github.com/BloggingApp/notification-service/internal/webpush"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+const (
+	MAX_PUSH_SUBSCRIPTIONS_PER_USER = 20
+	PUSH_SEND_TIMEOUT = time.Second * 10
+	PUSH_DELIVERY_WORKERS = 20
+)
+
+// pushes aren't retried: a retry would push again to every browser and device that already got it
+var pushDeliveryRetryPolicy = rabbitmq.RetryPolicy{MaxAttempts: 1, InitialDelay: time.Second * 30}
+
+type pushService struct {
+	logger *zap.Logger
+	repo *repository.Repository
+	webPush *webpush.Client
+}
+
+func newPushService(logger *zap.Logger, repo *repository.Repository, webPush *webpush.Client) Push {
+	return &pushService{
+		logger: logger,
+		repo: repo,
+		webPush: webPush,
+	}
+}
+
+func (s *pushService) VAPIDPublicKey() (string, error) {
+	if s.webPush == nil {
+		return "", ErrPushDisabled
+	}
+
+	return s.webPush.VAPIDPublicKey(), nil
+}
+
+func (s *pushService) RegisterSubscription(ctx context.Context, userID uuid.UUID, sub webpush.Subscription, userAgent string) (int64, error) {
+	if s.webPush == nil {
+		return 0, ErrPushDisabled
+	}
+	if err := sub.Validate(); err != nil {
+		return 0, ErrInvalidPushSubscription
+	}
+
+	subs, err := s.repo.Postgres.Push.GetUserSubscriptions(ctx, userID)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get user(%s)'s push subscriptions: %s", userID.String(), err.Error())
+		return 0, ErrInternal
+	}
+	if len(subs) >= MAX_PUSH_SUBSCRIPTIONS_PER_USER {
+		return 0, ErrTooManyPushSubscriptions
+	}
+
+	id, err := s.repo.Postgres.Push.CreateSubscription(ctx, model.PushSubscription{
+		UserID: userID,
+		Endpoint: sub.Endpoint,
+		P256dh: sub.Keys.P256dh,
+		Auth: sub.Keys.Auth,
+		UserAgent: userAgent,
+	})
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to create user(%s)'s push subscription: %s", userID.String(), err.Error())
+		return 0, ErrInternal
+	}
+
+	return id, nil
+}
+
+func (s *pushService) GetSubscriptions(ctx context.Context, userID uuid.UUID) ([]*model.PushSubscription, error) {
+	subs, err := s.repo.Postgres.Push.GetUserSubscriptions(ctx, userID)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get user(%s)'s push subscriptions: %s", userID.String(), err.Error())
+		return nil, ErrInternal
+	}
+
+	return subs, nil
+}
+
+func (s *pushService) RemoveSubscription(ctx context.Context, userID uuid.UUID, id int64) error {
+	if err := s.repo.Postgres.Push.DeleteSubscription(ctx, userID, id); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrPushSubscriptionNotFound
+		}
+
+		s.logger.Sugar().Errorf("failed to delete user(%s)'s push subscription(%d): %s", userID.String(), id, err.Error())
+		return ErrInternal
+	}
+
+	return nil
+}
+
+// queuePushDelivery hands the notification over to the push workers, which keeps slow
+// push services from holding up routing
+func (s *notificationService) queuePushDelivery(msg model.NotificationDelivery) {
+	deliveryJSON, err := json.Marshal(dto.MQPushDelivery{
+		ReceiverID: msg.ReceiverID,
+		Type: msg.Type,
+		Content: msg.Content,
+		ResourceID: msg.ResourceID,
+		Priority: msg.Priority,
+	})
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to marshal push delivery: %s", err.Error())
+		return
+	}
+
+	if err := s.rabbitmq.PublishToQueue(rabbitmq.PUSH_DELIVERY_QUEUE, deliveryJSON); err != nil {
+		s.logger.Sugar().Errorf("failed to queue push delivery to user(%s): %s", msg.ReceiverID.String(), err.Error())
+	}
+}
+
+// StartProcessingPushDeliveries sends queued pushes to the users' browsers and devices
+func (s *notificationService) StartProcessingPushDeliveries(ctx context.Context) {
+	worker := s.rabbitmq.NewWorker(rabbitmq.PUSH_DELIVERY_QUEUE, pushDeliveryRetryPolicy, nil)
+	worker.Run(PUSH_DELIVERY_WORKERS, PUSH_DELIVERY_WORKERS, func(msg amqp.Delivery) {
+		var data dto.MQPushDelivery
+		if err := json.Unmarshal(msg.Body, &data); err != nil {
+			s.logger.Sugar().Errorf("failed to unmarshal data from queue(%s) to json: %s", rabbitmq.PUSH_DELIVERY_QUEUE, err.Error())
+			worker.Fail(msg, rabbitmq.Permanent(err))
+			return
+		}
+
+		delivery := model.NotificationDelivery{
+			ReceiverID: data.ReceiverID,
+			Type: data.Type,
+			Content: data.Content,
+			ResourceID: data.ResourceID,
+			Priority: data.Priority,
+		}
+		s.pushDelivery(delivery)
+		s.mobilePushDelivery(delivery)
+
+		msg.Ack(false)
+	})
+}
+
+// pushDelivery sends the notification to every browser the user subscribed with,
+// pruning subscriptions the push service no longer knows
+func (s *notificationService) pushDelivery(msg model.NotificationDelivery) {
+	if s.webPush == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), PUSH_SEND_TIMEOUT)
+	defer cancel()
+
+	subs, err := s.repo.Postgres.Push.GetUserSubscriptions(ctx, msg.ReceiverID)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get user(%s)'s push subscriptions: %s", msg.ReceiverID.String(), err.Error())
+		return
+	}
+	if len(subs) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(deliveryPayload(msg))
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to marshal push payload: %s", err.Error())
+		return
+	}
+
+	for _, sub := range subs {
+		err := s.webPush.Send(ctx, webpush.Subscription{
+			Endpoint: sub.Endpoint,
+			Keys: webpush.Keys{P256dh: sub.P256dh, Auth: sub.Auth},
+		}, payload, webpush.Options{Urgency: webpush.URGENCY_NORMAL})
+		if err == webpush.ErrSubscriptionGone {
+			if err := s.repo.Postgres.Push.DeleteSubscriptionByEndpoint(ctx, sub.Endpoint); err != nil {
+				s.logger.Sugar().Errorf("failed to delete gone push subscription(%d): %s", sub.ID, err.Error())
+			}
+			continue
+		}
+		if err != nil {
+			s.logger.Sugar().Errorf("failed to push to user(%s)'s subscription(%d): %s", msg.ReceiverID.String(), sub.ID, err.Error())
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/repository"
+	"github.com/BloggingApp/notification-service/internal/repository/postgres"
+	"github.com/BloggingApp/notification-service/internal/webpush"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// fakePushRepo holds the subscriptions of a single user
+type fakePushRepo struct {
+	postgres.Push
+	mu      sync.Mutex
+	subs    []*model.PushSubscription
+	deleted []string
+}
+
+func (f *fakePushRepo) GetUserSubscriptions(ctx context.Context, userID uuid.UUID) ([]*model.PushSubscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.subs), nil
+}
+
+func (f *fakePushRepo) DeleteSubscriptionByEndpoint(ctx context.Context, endpoint string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, endpoint)
+	return nil
+}
+
+func testPushSubscription(t *testing.T, id int64, endpoint string) *model.PushSubscription {
+	t.Helper()
+
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := make([]byte, 16)
+	if _, err := rand.Read(auth); err != nil {
+		t.Fatal(err)
+	}
+
+	return &model.PushSubscription{
+		ID: id,
+		Endpoint: endpoint,
+		P256dh: base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		Auth: base64.RawURLEncoding.EncodeToString(auth),
+	}
+}
+
+func TestPushDeliveryPrunesGoneSubscriptions(t *testing.T) {
+	// the push service answers by path
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/not-found":
+			w.WriteHeader(http.StatusNotFound)
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/throttled":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer srv.Close()
+
+	privateKey, _, err := webpush.GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vapid, err := webpush.NewVAPID(privateKey, "mailto:push@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushRepo := &fakePushRepo{subs: []*model.PushSubscription{
+		testPushSubscription(t, 1, srv.URL+"/active"),
+		testPushSubscription(t, 2, srv.URL+"/not-found"),
+		testPushSubscription(t, 3, srv.URL+"/gone"),
+		testPushSubscription(t, 4, srv.URL+"/throttled"),
+	}}
+	s := &notificationService{
+		logger: zap.NewNop(),
+		repo: &repository.Repository{Postgres: &postgres.PGRepo{Push: pushRepo}},
+		webPush: webpush.NewClient(srv.Client(), vapid),
+	}
+
+	s.pushDelivery(model.NotificationDelivery{ReceiverID: uuid.New(), Type: "new_follower", Content: "jane followed you"})
+
+	expected := []string{srv.URL + "/gone", srv.URL + "/not-found"}
+	slices.Sort(pushRepo.deleted)
+	if !slices.Equal(pushRepo.deleted, expected) {
+		t.Fatalf("expected %v pruned, got %v", expected, pushRepo.deleted)
+	}
+}
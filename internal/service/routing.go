@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/repository/redisrepo"
+	"github.com/BloggingApp/notification-service/internal/routing"
+	"github.com/go-co-op/gocron/v2"
+	"github.com/google/uuid"
+)
+
+const (
+	ROUTING_TIMEOUT = time.Second * 10
+	// a fan-out is routed in batches, so a batch's lookups take a few queries instead of a few per receiver
+	ROUTING_BATCH_SIZE = 500
+	ROUTING_WORKERS = 5
+)
+
+// notificationPriorities are the priorities of notification types, the ones missing are normal
+var notificationPriorities = map[string]string{
+	NEW_POST_NOTIFICATION_TYPE: routing.PRIORITY_NORMAL,
+	POST_VALIDATION_STATUS_UPDATE_TYPE: routing.PRIORITY_HIGH,
+}
+
+// deliver hands stored notifications over to the routing engine. Every producer of per-user
+// notifications goes through it, global notifications are broadcast instead (see broadcastGlobalNotification).
+func (s *notificationService) deliver(msgs ...model.NotificationDelivery) {
+	for i := range msgs {
+		if msgs[i].Priority == "" {
+			msgs[i].Priority = notificationPriorities[msgs[i].Type]
+		}
+		if msgs[i].Priority == "" {
+			msgs[i].Priority = routing.PRIORITY_NORMAL
+		}
+	}
+
+	for batch := range slices.Chunk(msgs, ROUTING_BATCH_SIZE) {
+		s.deliveryChan <- batch
+	}
+}
+
+// route decides the channel of every notification of the batch, sends it there and records the decisions.
+// A user is online while any replica holds their socket. Notifications for a socket on another replica
+// are forwarded to it, and a socket that fails to take a notification gets it routed again as if the user
+// was offline. Pushes are only queued here, the push workers send them.
+func (s *notificationService) route(msgs []model.NotificationDelivery) {
+	ctx, cancel := context.WithTimeout(context.Background(), ROUTING_TIMEOUT)
+	defer cancel()
+
+	userIDs := receivers(msgs)
+	prefs := s.routingPreferences(ctx, userIDs)
+	pushTargets := s.pushTargets(ctx, userIDs)
+	socketReplicas := s.socketReplicas(ctx, userIDs)
+
+	decisions := make([]model.RoutingDecision, 0, len(msgs))
+	for _, msg := range msgs {
+		conn := s.localConn(msg.ReceiverID)
+		replicaID := socketReplicas[msg.ReceiverID]
+		online := conn != nil || replicaID != ""
+		decision := s.decide(msg, prefs[msg.ReceiverID], online, pushTargets[msg.ReceiverID])
+
+		if decision.Channel == routing.CHANNEL_WEBSOCKET {
+			if err := s.writeSocket(ctx, conn, replicaID, msg); err != nil {
+				s.logger.Sugar().Errorf("failed to write json msg to receiver(%s)'s conn: %s", msg.ReceiverID.String(), err.Error())
+				decision = s.decide(msg, prefs[msg.ReceiverID], false, pushTargets[msg.ReceiverID])
+				decision.Reason = "socket write failed, " + decision.Reason
+			}
+		}
+
+		if decision.Channel == routing.CHANNEL_PUSH {
+			s.queuePushDelivery(msg)
+		}
+		// digest and inbox notifications are already stored, the digest job picks up unread ones
+
+		decisions = append(decisions, routingDecision(msg, decision, online))
+	}
+
+	if err := s.repo.Postgres.Routing.CreateDecisions(ctx, decisions); err != nil {
+		s.logger.Sugar().Errorf("failed to record %d routing decisions: %s", len(decisions), err.Error())
+	}
+}
+
+// routeOffline routes a notification whose socket went away after it was routed, as if the user was offline
+func (s *notificationService) routeOffline(msg model.NotificationDelivery, reason string) {
+	ctx, cancel := context.WithTimeout(context.Background(), ROUTING_TIMEOUT)
+	defer cancel()
+
+	userIDs := []uuid.UUID{msg.ReceiverID}
+	prefs := s.routingPreferences(ctx, userIDs)
+	decision := s.decide(msg, prefs[msg.ReceiverID], false, s.pushTargets(ctx, userIDs)[msg.ReceiverID])
+	decision.Reason = reason + ", " + decision.Reason
+
+	if decision.Channel == routing.CHANNEL_PUSH {
+		s.queuePushDelivery(msg)
+	}
+
+	if err := s.repo.Postgres.Routing.CreateDecisions(ctx, []model.RoutingDecision{routingDecision(msg, decision, false)}); err != nil {
+		s.logger.Sugar().Errorf("failed to record routing decision: %s", err.Error())
+	}
+}
+
+func routingDecision(msg model.NotificationDelivery, decision routing.Decision, online bool) model.RoutingDecision {
+	return model.RoutingDecision{
+		UserID: msg.ReceiverID,
+		Type: msg.Type,
+		ResourceID: msg.ResourceID,
+		Priority: msg.Priority,
+		Channel: decision.Channel,
+		Reason: decision.Reason,
+		Online: online,
+		QuietHours: decision.QuietHours,
+	}
+}
+
+// writeSocket writes the notification to the receiver's socket on this replica, or forwards it to replicaID when there is none here
+func (s *notificationService) writeSocket(ctx context.Context, conn *wsConn, replicaID string, msg model.NotificationDelivery) error {
+	if conn != nil {
+		return conn.writeJSON(deliveryPayload(msg))
+	}
+
+	msgJSON, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	listening, err := s.rdb.Publish(ctx, redisrepo.ReplicaDeliveriesChannel(replicaID), msgJSON).Result()
+	if err != nil {
+		return err
+	}
+	// the replica is gone, its presence hasn't expired yet
+	if listening == 0 {
+		return fmt.Errorf("replica(%s) holding the socket isn't listening", replicaID)
+	}
+
+	return nil
+}
+
+// StartReceivingForwardedDeliveries writes the notifications other replicas routed to the sockets this replica holds.
+// A socket closed since is routed again as if the user was offline.
+func (s *notificationService) StartReceivingForwardedDeliveries(ctx context.Context) {
+	channel := redisrepo.ReplicaDeliveriesChannel(s.replicaID)
+	pubsub := s.rdb.Subscribe(ctx, channel)
+	defer pubsub.Close()
+
+	for msg := range pubsub.Channel() {
+		var delivery model.NotificationDelivery
+		if err := json.Unmarshal([]byte(msg.Payload), &delivery); err != nil {
+			s.logger.Sugar().Errorf("failed to unmarshal data from redis channel(%s) to json: %s", channel, err.Error())
+			continue
+		}
+
+		conn := s.localConn(delivery.ReceiverID)
+		if conn == nil {
+			s.routeOffline(delivery, "socket closed before forwarded write")
+			continue
+		}
+		if err := conn.writeJSON(deliveryPayload(delivery)); err != nil {
+			s.logger.Sugar().Errorf("failed to write json msg to receiver(%s)'s conn: %s", delivery.ReceiverID.String(), err.Error())
+			s.routeOffline(delivery, "forwarded socket write failed")
+		}
+	}
+}
+
+func (s *notificationService) decide(msg model.NotificationDelivery, prefs *model.NotificationPreferences, online bool, hasPushTargets bool) routing.Decision {
+	return routing.Decide(routing.Input{
+		Type: msg.Type,
+		Priority: msg.Priority,
+		Online: online,
+		HasPushTargets: hasPushTargets,
+		Preferences: prefs,
+		Now: time.Now(),
+	})
+}
+
+func (s *notificationService) localConn(userID uuid.UUID) *wsConn {
+	val, ok := s.conns.Load(userID)
+	if !ok {
+		return nil
+	}
+
+	conn, _ := val.(*wsConn)
+	return conn
+}
+
+// socketReplicas returns the other replica holding a socket of each user who has one there.
+// Without Redis only this replica's sockets count.
+func (s *notificationService) socketReplicas(ctx context.Context, userIDs []uuid.UUID) map[uuid.UUID]string {
+	ids := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		ids[i] = userID.String()
+	}
+
+	replicas, err := redisrepo.GetSocketReplicas(s.rdb, ctx, ids, s.replicaID)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get %d users' socket replicas from redis: %s", len(userIDs), err.Error())
+	}
+
+	socketReplicas := make(map[uuid.UUID]string, len(replicas))
+	for id, replicaID := range replicas {
+		userID, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		socketReplicas[userID] = replicaID
+	}
+
+	return socketReplicas
+}
+
+// receivers returns the distinct receivers of the notifications
+func receivers(msgs []model.NotificationDelivery) []uuid.UUID {
+	userIDs := make([]uuid.UUID, 0, len(msgs))
+	seen := make(map[uuid.UUID]bool, len(msgs))
+	for _, msg := range msgs {
+		if !seen[msg.ReceiverID] {
+			seen[msg.ReceiverID] = true
+			userIDs = append(userIDs, msg.ReceiverID)
+		}
+	}
+
+	return userIDs
+}
+
+// routingPreferences returns every user's preferences, the defaults for users without saved ones
+func (s *notificationService) routingPreferences(ctx context.Context, userIDs []uuid.UUID) map[uuid.UUID]*model.NotificationPreferences {
+	prefs := make(map[uuid.UUID]*model.NotificationPreferences, len(userIDs))
+
+	saved, err := s.repo.Postgres.Preferences.GetMany(ctx, userIDs)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get %d users' notification preferences for routing: %s", len(userIDs), err.Error())
+	}
+	for _, p := range saved {
+		prefs[p.UserID] = p
+	}
+
+	for _, userID := range userIDs {
+		if _, ok := prefs[userID]; !ok {
+			prefs[userID] = model.DefaultNotificationPreferences(userID)
+		}
+	}
+
+	return prefs
+}
+
+// pushTargets tells which of the users any configured push channel can reach
+func (s *notificationService) pushTargets(ctx context.Context, userIDs []uuid.UUID) map[uuid.UUID]bool {
+	targets := make(map[uuid.UUID]bool)
+
+	if s.webPush != nil {
+		subscribed, err := s.repo.Postgres.Push.GetSubscribedUsers(ctx, userIDs)
+		if err != nil {
+			s.logger.Sugar().Errorf("failed to get %d users' push subscriptions: %s", len(userIDs), err.Error())
+		}
+		for _, userID := range subscribed {
+			targets[userID] = true
+		}
+	}
+
+	if len(s.mobilePush) > 0 {
+		withTokens, err := s.repo.Postgres.Device.GetUsersWithTokens(ctx, userIDs, slices.Collect(maps.Keys(s.mobilePush)))
+		if err != nil {
+			s.logger.Sugar().Errorf("failed to get %d users' device tokens: %s", len(userIDs), err.Error())
+		}
+		for _, userID := range withTokens {
+			targets[userID] = true
+		}
+	}
+
+	return targets
+}
+
+func (s *notificationService) GetRoutingDecisions(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.RoutingDecision, error) {
+	decisions, err := s.repo.Postgres.Routing.GetUserDecisions(ctx, userID, limit, offset)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get user(%s)'s routing decisions: %s", userID.String(), err.Error())
+		return nil, ErrInternal
+	}
+
+	return decisions, nil
+}
+
+func (s *notificationService) newDeleteOldRoutingDecisionsJob() {
+	s.scheduler.NewJob(gocron.DurationJob(time.Hour * 12), gocron.NewTask(func(ctx context.Context) {
+		if err := s.repo.Postgres.Routing.DeleteOldDecisions(ctx); err != nil {
+			s.logger.Sugar().Errorf("failed to delete old routing decisions: %s", err.Error())
+		}
+	}))
+}
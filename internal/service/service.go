@@ -2,10 +2,19 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
+	"github.com/BloggingApp/notification-service/internal/config"
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/mailer"
+	"github.com/BloggingApp/notification-service/internal/mobilepush"
 	"github.com/BloggingApp/notification-service/internal/model"
 	"github.com/BloggingApp/notification-service/internal/rabbitmq"
 	"github.com/BloggingApp/notification-service/internal/repository"
+	"github.com/BloggingApp/notification-service/internal/sms"
+	"github.com/BloggingApp/notification-service/internal/unsubscribe"
+	"github.com/BloggingApp/notification-service/internal/webpush"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/redis/go-redis/v9"
@@ -27,21 +36,120 @@ type Notification interface {
 	UnregisterConnection(userID uuid.UUID)
 	StartProcessingNewPostNotifications(ctx context.Context)
 	GetUserNotifications(ctx context.Context, userID uuid.UUID, limit int, offset int) ([]*model.Notification, error)
+	MarkAsRead(ctx context.Context, userID uuid.UUID, notificationID int64) error
 	StartJobs()
-	CreateGlobalNotification(ctx context.Context, gn model.GlobalNotification) error
+	CreateGlobalNotification(ctx context.Context, gn model.GlobalNotification) (int64, error)
+	CountGlobalNotificationAudience(ctx context.Context, audience *model.GlobalNotificationAudience) (int64, error)
 	GetGlobalNotifications(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.GlobalNotification, error)
 	MarkGlobalNotificationAsRead(ctx context.Context, userID uuid.UUID, notificationID int64) error
+	ListGlobalNotifications(ctx context.Context, status string, limit, offset int) ([]*model.GlobalNotification, error)
+	UpdateGlobalNotification(ctx context.Context, id int64, editorID uuid.UUID, input dto.UpdateGlobalNotification) (*model.GlobalNotification, error)
+	DeleteGlobalNotification(ctx context.Context, id int64, adminID uuid.UUID) error
+	SubmitGlobalNotification(ctx context.Context, id int64, adminID uuid.UUID, comment string) error
+	ApproveGlobalNotification(ctx context.Context, id int64, adminID uuid.UUID, comment string) error
+	RejectGlobalNotification(ctx context.Context, id int64, adminID uuid.UUID, comment string) error
+	PublishGlobalNotification(ctx context.Context, id int64, adminID uuid.UUID, comment string) error
+	GetGlobalNotificationAuditTrail(ctx context.Context, id int64) ([]*model.GlobalNotificationEvent, error)
+	GetGlobalNotificationStats(ctx context.Context, id int64, interval string) (*model.GlobalNotificationStats, error)
 	StartProcessingPostValidationStatusUpdates(ctx context.Context)
+	StartBroadcastingGlobalNotifications(ctx context.Context)
+	StartReceivingForwardedDeliveries(ctx context.Context)
+	StartProcessingPushDeliveries(ctx context.Context)
+	GetRoutingDecisions(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*model.RoutingDecision, error)
+	StartPresenceHeartbeats(ctx context.Context)
+}
+
+type Preferences interface {
+	Get(ctx context.Context, userID uuid.UUID) (*model.NotificationPreferences, error)
+	Update(ctx context.Context, userID uuid.UUID, input dto.UpdateNotificationPreferences) (*model.NotificationPreferences, error)
+	CheckUnsubscribeToken(token string) (*unsubscribe.Claims, error)
+	Unsubscribe(ctx context.Context, token string) (*unsubscribe.Claims, error)
+}
+
+type Suppression interface {
+	List(ctx context.Context, email string, limit, offset int) ([]*model.EmailSuppression, error)
+	Remove(ctx context.Context, email string, adminID uuid.UUID) error
+}
+
+type EmailAudit interface {
+	Search(ctx context.Context, recipient string, from, to time.Time, limit, offset int) ([]*model.EmailAuditRecord, error)
+}
+
+type Mail interface {
+	ListEmailTypes() []string
+	PreviewEmail(ctx context.Context, typeName string, data json.RawMessage) ([]*mailer.Preview, error)
+	SendTestEmail(ctx context.Context, adminID uuid.UUID, typeName string, to string, data json.RawMessage) ([]string, error)
+}
+
+type Push interface {
+	VAPIDPublicKey() (string, error)
+	RegisterSubscription(ctx context.Context, userID uuid.UUID, sub webpush.Subscription, userAgent string) (int64, error)
+	GetSubscriptions(ctx context.Context, userID uuid.UUID) ([]*model.PushSubscription, error)
+	RemoveSubscription(ctx context.Context, userID uuid.UUID, id int64) error
+}
+
+type Device interface {
+	RegisterDevice(ctx context.Context, userID uuid.UUID, platform string, token string) (int64, error)
+	GetDevices(ctx context.Context, userID uuid.UUID) ([]*model.DeviceToken, error)
+	RemoveDevice(ctx context.Context, userID uuid.UUID, id int64) error
+}
+
+type Webhook interface {
+	CreateWebhook(ctx context.Context, userID uuid.UUID, input dto.CreateWebhook) (*model.Webhook, error)
+	GetWebhooks(ctx context.Context, userID uuid.UUID) ([]*model.Webhook, error)
+	UpdateWebhook(ctx context.Context, userID uuid.UUID, id int64, input dto.UpdateWebhook) (*model.Webhook, error)
+	DeleteWebhook(ctx context.Context, userID uuid.UUID, id int64) error
+	GetWebhookDeliveries(ctx context.Context, userID uuid.UUID, id int64, limit, offset int) ([]*model.WebhookDelivery, error)
+	StartProcessingDeliveries(ctx context.Context)
+}
+
+type SMS interface {
+	UpdateDeliveryStatus(ctx context.Context, token string, input dto.SMSStatusCallback) error
+}
+
+type DeadLetter interface {
+	GetDeadLetterQueues(ctx context.Context) ([]*model.DeadLetterQueue, error)
+	GetDeadLetters(ctx context.Context, queue string, limit int) ([]*model.DeadLetter, error)
+	GetDeadLetter(ctx context.Context, queue string, id string) (*model.DeadLetter, error)
+	ReplayDeadLetters(ctx context.Context, queue string, adminID uuid.UUID) (int, error)
+	ReplayDeadLetter(ctx context.Context, queue string, id string, adminID uuid.UUID) error
+	PurgeDeadLetters(ctx context.Context, queue string, adminID uuid.UUID) (int, error)
+	PurgeDeadLetter(ctx context.Context, queue string, id string, adminID uuid.UUID) error
+}
+
+type Presence interface {
+	GetPresence(ctx context.Context, userID uuid.UUID) (*model.Presence, error)
+	GetPresences(ctx context.Context, userIDs []uuid.UUID) ([]*model.Presence, error)
 }
 
 type Service struct {
 	User
 	Notification
+	Preferences
+	Suppression
+	EmailAudit
+	Mail
+	Push
+	Device
+	Webhook
+	SMS
+	Presence
+	DeadLetter
 }
 
-func New(logger *zap.Logger, repo *repository.Repository, rdb *redis.Client, rabbitmq *rabbitmq.MQConn) *Service {
+func New(logger *zap.Logger, repo *repository.Repository, rdb *redis.Client, rabbitmq *rabbitmq.MQConn, unsubscribeSigner *unsubscribe.Signer, mailer *mailer.Mailer, smsSender *sms.Sender, webPush *webpush.Client, mobilePush map[string]mobilepush.Provider, webhookConfig config.WebhookConfig) *Service {
 	return &Service{
 		User: newUserService(logger, repo, rdb, rabbitmq),
-		Notification: newNotificationService(logger, repo, rdb, rabbitmq),
+		Notification: newNotificationService(logger, repo, rdb, rabbitmq, webPush, mobilePush),
+		Preferences: newPreferencesService(logger, repo, unsubscribeSigner),
+		Suppression: newSuppressionService(logger, repo),
+		EmailAudit: newEmailAuditService(logger, repo),
+		Mail: newMailService(logger, mailer),
+		Push: newPushService(logger, repo, webPush),
+		Device: newDeviceService(logger, repo, mobilePush),
+		Webhook: newWebhookService(logger, repo, rabbitmq, webhookConfig),
+		SMS: newSMSService(logger, repo, smsSender),
+		Presence: newPresenceService(logger, rdb),
+		DeadLetter: newDeadLetterService(logger, rabbitmq),
 	}
 }
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/repository"
+	"github.com/BloggingApp/notification-service/internal/sms"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// smsCallbackStatuses maps the statuses the gateway reports to the stored ones.
+// A failure reported by the gateway is final, it doesn't retry.
+var smsCallbackStatuses = map[string]string{
+	"sent": model.SMS_STATUS_SENT,
+	"delivered": model.SMS_STATUS_DELIVERED,
+	"undelivered": model.SMS_STATUS_UNDELIVERED,
+	"failed": model.SMS_STATUS_UNDELIVERED,
+}
+
+type smsService struct {
+	logger *zap.Logger
+	repo *repository.Repository
+	sender *sms.Sender
+}
+
+func newSMSService(logger *zap.Logger, repo *repository.Repository, sender *sms.Sender) SMS {
+	return &smsService{
+		logger: logger,
+		repo: repo,
+		sender: sender,
+	}
+}
+
+func (s *smsService) UpdateDeliveryStatus(ctx context.Context, token string, input dto.SMSStatusCallback) error {
+	if !s.sender.CheckCallbackToken(token) {
+		return ErrInvalidSMSCallbackToken
+	}
+
+	status, ok := smsCallbackStatuses[input.Status]
+	if !ok || input.ID == "" {
+		return ErrInvalidSMSStatus
+	}
+
+	if err := s.repo.Postgres.SMS.UpdateStatus(ctx, input.ID, status, input.Error); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrSMSMessageNotFound
+		}
+
+		s.logger.Sugar().Errorf("failed to update sms message(%s) status: %s", input.ID, err.Error())
+		return ErrInternal
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/repository"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+type suppressionService struct {
+	logger *zap.Logger
+	repo *repository.Repository
+}
+
+func newSuppressionService(logger *zap.Logger, repo *repository.Repository) Suppression {
+	return &suppressionService{
+		logger: logger,
+		repo: repo,
+	}
+}
+
+func (s *suppressionService) List(ctx context.Context, email string, limit, offset int) ([]*model.EmailSuppression, error) {
+	suppressions, err := s.repo.Postgres.Suppression.List(ctx, email, limit, offset)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to list email suppressions: %s", err.Error())
+		return nil, ErrInternal
+	}
+
+	return suppressions, nil
+}
+
+func (s *suppressionService) Remove(ctx context.Context, email string, adminID uuid.UUID) error {
+	if err := s.repo.Postgres.Suppression.Delete(ctx, email); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrSuppressionNotFound
+		}
+
+		s.logger.Sugar().Errorf("failed to remove email suppression(%s): %s", email, err.Error())
+		return ErrInternal
+	}
+
+	s.logger.Sugar().Infof("admin(%s) removed email suppression(%s)", adminID.String(), email)
+
+	return nil
+}
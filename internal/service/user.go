@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/BloggingApp/notification-service/internal/dto"
 	"github.com/BloggingApp/notification-service/internal/model"
@@ -43,7 +44,7 @@ func (s *userService) updateByID(ctx context.Context, id uuid.UUID, updates map[
 		return nil
 	}
 
-	allowedFields := []string{"username", "display_name", "avatar_url"}
+	allowedFields := []string{"username", "display_name", "avatar_url", "email", "locale"}
 	allowedFieldsSet := make(map[string]struct{}, len(allowedFields))
 	for _, field := range allowedFields {
 		allowedFieldsSet[field] = struct{}{}
@@ -63,7 +64,7 @@ func (s *userService) updateByID(ctx context.Context, id uuid.UUID, updates map[
 }
 
 func (s *userService) StartCreating(ctx context.Context) {
-	msgs, err := s.rabbitmq.ConsumeExchange(rabbitmq.USERS_CREATED_EXCHANGE)
+	msgs, err := s.rabbitmq.ConsumeExchangeQueue(rabbitmq.USERS_CREATED_EXCHANGE, rabbitmq.USERS_CREATED_QUEUE)
 	if err != nil {
 		panic(err)
 	}
@@ -71,18 +72,26 @@ func (s *userService) StartCreating(ctx context.Context) {
 	for msg := range msgs {
 		var userCreatedDto dto.MQUserCreated
 		if err := json.Unmarshal(msg.Body, &userCreatedDto); err != nil {
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.USERS_CREATED_QUEUE, msg, err)
 			continue
 		}
 
+		createdAt := userCreatedDto.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+
 		if err := s.create(ctx, model.User{
 			ID: userCreatedDto.ID,
 			Username: userCreatedDto.Username,
 			DisplayName: userCreatedDto.DisplayName,
 			AvatarURL: userCreatedDto.AvatarURL,
+			Email: userCreatedDto.Email,
+			Locale: userCreatedDto.Locale,
+			CreatedAt: createdAt,
 		}); err != nil {
 			s.logger.Sugar().Errorf("failed to create user(%s): %s", userCreatedDto.ID.String(), err.Error())
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.USERS_CREATED_QUEUE, msg, err)
 			continue
 		}
 
@@ -91,7 +100,7 @@ func (s *userService) StartCreating(ctx context.Context) {
 }
 
 func (s *userService) StartUpdating(ctx context.Context) {
-	msgs, err := s.rabbitmq.ConsumeExchange(rabbitmq.USERS_UPDATE_EXCHANGE)
+	msgs, err := s.rabbitmq.ConsumeExchangeQueue(rabbitmq.USERS_UPDATE_EXCHANGE, rabbitmq.USERS_UPDATE_QUEUE)
 	if err != nil {
 		panic(err)
 	}
@@ -99,18 +108,18 @@ func (s *userService) StartUpdating(ctx context.Context) {
 	for msg := range msgs {
 		var updates map[string]interface{}
 		if err := json.Unmarshal(msg.Body, &updates); err != nil {
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.USERS_UPDATE_QUEUE, msg, err)
 			continue
 		}
 
 		userIDString, ok := updates["user_id"].(string)
 		if !ok {
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.USERS_UPDATE_QUEUE, msg, ErrInvalidUserID)
 			continue
 		}
 		userID, err := uuid.Parse(userIDString)
 		if err != nil {
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.USERS_UPDATE_QUEUE, msg, err)
 			continue
 		}
 
@@ -118,7 +127,7 @@ func (s *userService) StartUpdating(ctx context.Context) {
 
 		if err := s.updateByID(ctx, userID, updates); err != nil {
 			s.logger.Sugar().Errorf("failed to update user(%s): %s", userID.String(), err.Error())
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.USERS_UPDATE_QUEUE, msg, err)
 			continue
 		}
 
@@ -135,7 +144,7 @@ func (s *userService) StartCreatingFollowers(ctx context.Context) {
 	for msg := range msgs {
 		var follower dto.MQFollow
 		if err := json.Unmarshal(msg.Body, &follower); err != nil {
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.FOLLOWS_QUEUE, msg, err)
 			continue
 		}
 
@@ -145,7 +154,7 @@ func (s *userService) StartCreatingFollowers(ctx context.Context) {
 			NewPostNotificationsEnabled: false,
 		}); err != nil {
 			s.logger.Sugar().Errorf("failed to create new follower(%s) who follows user(%s): %s", follower.FollowerID.String(), follower.UserID.String(), err.Error())
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.FOLLOWS_QUEUE, msg, err)
 			continue
 		}
 
@@ -162,7 +171,7 @@ func (s *userService) StartUpdatingFollowersNewPostNotificationsEnabled(ctx cont
 	for msg := range msgs {
 		var update dto.MQNewPostNotificationsEnabledUpdate
 		if err := json.Unmarshal(msg.Body, &update); err != nil {
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.FOLLOWERS_NEW_POST_NOTIFICATIONS_ENABLED_UPDATES_QUEUE, msg, err)
 			continue
 		}
 
@@ -172,7 +181,7 @@ func (s *userService) StartUpdatingFollowersNewPostNotificationsEnabled(ctx cont
 			NewPostNotificationsEnabled: update.Enabled,
 		}); err != nil {
 			s.logger.Sugar().Errorf("failed to update follower(%s)'s new_post_notifications_enabled for author(%s): %s", update.FollowerID.String(), update.UserID.String(), err.Error())
-			msg.Ack(false)
+			s.rabbitmq.Reject(rabbitmq.FOLLOWERS_NEW_POST_NOTIFICATIONS_ENABLED_UPDATES_QUEUE, msg, err)
 			continue
 		}
 
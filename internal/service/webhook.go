@@ -0,0 +1,397 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/config"
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+	"github.com/BloggingApp/notification-service/internal/repository"
+	"github.com/BloggingApp/notification-service/internal/webhook"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+const (
+	MAX_WEBHOOKS_PER_USER = 10
+
+	DEFAULT_WEBHOOK_TIMEOUT = time.Second * 10
+	DEFAULT_WEBHOOK_RETRY_MAX_ATTEMPTS = 8
+	DEFAULT_WEBHOOK_RETRY_INITIAL_DELAY = time.Second * 30
+	DEFAULT_WEBHOOK_MAX_FAILURES = 20
+)
+
+// webhookNotificationTypes are the notification types users can subscribe webhooks to
+var webhookNotificationTypes = []string{NEW_POST_NOTIFICATION_TYPE, POST_VALIDATION_STATUS_UPDATE_TYPE}
+
+// webhookEvent is the body posted to webhooks
+type webhookEvent struct {
+	ID         uuid.UUID `json:"id"`
+	Type       string    `json:"type"`
+	UserID     uuid.UUID `json:"user_id"`
+	Content    string    `json:"content"`
+	ResourceID string    `json:"resource_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type webhookService struct {
+	logger *zap.Logger
+	repo *repository.Repository
+	rabbitmq *rabbitmq.MQConn
+	client *webhook.Client
+	cfg config.WebhookConfig
+}
+
+func newWebhookService(logger *zap.Logger, repo *repository.Repository, rabbitmq *rabbitmq.MQConn, cfg config.WebhookConfig) Webhook {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DEFAULT_WEBHOOK_TIMEOUT
+	}
+	if cfg.RetryMaxAttempts <= 0 {
+		cfg.RetryMaxAttempts = DEFAULT_WEBHOOK_RETRY_MAX_ATTEMPTS
+	}
+	if cfg.RetryInitialDelay <= 0 {
+		cfg.RetryInitialDelay = DEFAULT_WEBHOOK_RETRY_INITIAL_DELAY
+	}
+	if cfg.MaxFailures <= 0 {
+		cfg.MaxFailures = DEFAULT_WEBHOOK_MAX_FAILURES
+	}
+
+	return &webhookService{
+		logger: logger,
+		repo: repo,
+		rabbitmq: rabbitmq,
+		client: webhook.NewClient(cfg.Timeout),
+		cfg: cfg,
+	}
+}
+
+func validateWebhook(url string, types []string) error {
+	if err := webhook.ValidateURL(url); err != nil {
+		return ErrInvalidWebhookURL
+	}
+
+	if len(types) == 0 {
+		return ErrInvalidWebhookTypes
+	}
+	for _, t := range types {
+		if !slices.Contains(webhookNotificationTypes, t) {
+			return ErrInvalidWebhookTypes
+		}
+	}
+
+	return nil
+}
+
+// CreateWebhook returns the webhook with its signing secret, the only time the secret is shown
+func (s *webhookService) CreateWebhook(ctx context.Context, userID uuid.UUID, input dto.CreateWebhook) (*model.Webhook, error) {
+	if err := validateWebhook(input.URL, input.Types); err != nil {
+		return nil, err
+	}
+
+	webhooks, err := s.repo.Postgres.Webhook.GetUserWebhooks(ctx, userID)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get user(%s)'s webhooks: %s", userID.String(), err.Error())
+		return nil, ErrInternal
+	}
+	if len(webhooks) >= MAX_WEBHOOKS_PER_USER {
+		return nil, ErrTooManyWebhooks
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to generate webhook secret: %s", err.Error())
+		return nil, ErrInternal
+	}
+
+	created, err := s.repo.Postgres.Webhook.Create(ctx, model.Webhook{
+		UserID: userID,
+		URL: input.URL,
+		Secret: secret,
+		Types: slices.Compact(slices.Sorted(slices.Values(input.Types))),
+	})
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to create user(%s)'s webhook: %s", userID.String(), err.Error())
+		return nil, ErrInternal
+	}
+
+	return created, nil
+}
+
+func (s *webhookService) GetWebhooks(ctx context.Context, userID uuid.UUID) ([]*model.Webhook, error) {
+	webhooks, err := s.repo.Postgres.Webhook.GetUserWebhooks(ctx, userID)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get user(%s)'s webhooks: %s", userID.String(), err.Error())
+		return nil, ErrInternal
+	}
+
+	for _, w := range webhooks {
+		w.Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+// findUserWebhook finds the webhook if it belongs to the user
+func (s *webhookService) findUserWebhook(ctx context.Context, userID uuid.UUID, id int64) (*model.Webhook, error) {
+	w, err := s.repo.Postgres.Webhook.FindByID(ctx, id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrWebhookNotFound
+		}
+
+		s.logger.Sugar().Errorf("failed to find webhook(%d): %s", id, err.Error())
+		return nil, ErrInternal
+	}
+
+	if w.UserID != userID {
+		return nil, ErrWebhookNotFound
+	}
+
+	return w, nil
+}
+
+func (s *webhookService) UpdateWebhook(ctx context.Context, userID uuid.UUID, id int64, input dto.UpdateWebhook) (*model.Webhook, error) {
+	w, err := s.findUserWebhook(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.URL != nil {
+		w.URL = *input.URL
+	}
+	if input.Types != nil {
+		w.Types = slices.Compact(slices.Sorted(slices.Values(input.Types)))
+	}
+	if input.Enabled != nil {
+		w.Enabled = *input.Enabled
+	}
+
+	if err := validateWebhook(w.URL, w.Types); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Postgres.Webhook.Update(ctx, *w); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrWebhookNotFound
+		}
+
+		s.logger.Sugar().Errorf("failed to update user(%s)'s webhook(%d): %s", userID.String(), id, err.Error())
+		return nil, ErrInternal
+	}
+
+	// read back, enabling resets the failures
+	updated, err := s.findUserWebhook(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	updated.Secret = ""
+
+	return updated, nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, userID uuid.UUID, id int64) error {
+	if err := s.repo.Postgres.Webhook.Delete(ctx, userID, id); err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrWebhookNotFound
+		}
+
+		s.logger.Sugar().Errorf("failed to delete user(%s)'s webhook(%d): %s", userID.String(), id, err.Error())
+		return ErrInternal
+	}
+
+	return nil
+}
+
+func (s *webhookService) GetWebhookDeliveries(ctx context.Context, userID uuid.UUID, id int64, limit, offset int) ([]*model.WebhookDelivery, error) {
+	if _, err := s.findUserWebhook(ctx, userID, id); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.repo.Postgres.Webhook.GetDeliveries(ctx, id, limit, offset)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to get webhook(%d) deliveries: %s", id, err.Error())
+		return nil, ErrInternal
+	}
+
+	return deliveries, nil
+}
+
+func (s *webhookService) retryPolicy() rabbitmq.RetryPolicy {
+	return rabbitmq.RetryPolicy{
+		MaxAttempts: s.cfg.RetryMaxAttempts,
+		InitialDelay: s.cfg.RetryInitialDelay,
+	}
+}
+
+// StartProcessingDeliveries posts queued events to their webhooks. Failed attempts are retried
+// with backoff through the delay queues, so they survive restarts.
+func (s *webhookService) StartProcessingDeliveries(ctx context.Context) {
+	if err := s.rabbitmq.DeclareRetryQueues(rabbitmq.WEBHOOK_DELIVERY_QUEUE, s.retryPolicy()); err != nil {
+		panic(err)
+	}
+
+	workers := max(s.cfg.Workers, 1)
+	msgs, err := s.rabbitmq.ConsumeWithPrefetch(rabbitmq.WEBHOOK_DELIVERY_QUEUE, workers)
+	if err != nil {
+		panic(err)
+	}
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for msg := range msgs {
+				s.processDelivery(ctx, msg)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *webhookService) processDelivery(ctx context.Context, msg amqp.Delivery) {
+	var data dto.MQWebhookDelivery
+	if err := json.Unmarshal(msg.Body, &data); err != nil {
+		s.logger.Sugar().Errorf("failed to unmarshal data from queue(%s) to json: %s", rabbitmq.WEBHOOK_DELIVERY_QUEUE, err.Error())
+		s.rabbitmq.Reject(rabbitmq.WEBHOOK_DELIVERY_QUEUE, msg, err)
+		return
+	}
+
+	w, err := s.repo.Postgres.Webhook.FindByID(ctx, data.WebhookID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			msg.Ack(false)
+			return
+		}
+
+		s.logger.Sugar().Errorf("failed to find webhook(%d): %s", data.WebhookID, err.Error())
+		s.retryDelivery(msg, err)
+		return
+	}
+	// a webhook disabled while the event waited to be retried doesn't get it anymore
+	if !w.Enabled {
+		msg.Ack(false)
+		return
+	}
+
+	attempt := rabbitmq.Attempt(msg)
+	delivery := model.WebhookDelivery{
+		WebhookID: w.ID,
+		EventID: data.EventID,
+		Type: data.Type,
+		Attempt: attempt,
+	}
+
+	resp, sendErr := s.client.Send(ctx, w.URL, w.Secret, data.EventID.String(), data.Payload)
+	if sendErr == nil {
+		sendErr = resp.Err()
+		delivery.StatusCode = &resp.StatusCode
+		delivery.Response = resp.Body
+		delivery.DurationMs = resp.Duration.Milliseconds()
+	}
+	delivery.Success = sendErr == nil
+	if sendErr != nil {
+		delivery.Error = sendErr.Error()
+	}
+
+	disabled, err := s.repo.Postgres.Webhook.RecordDelivery(ctx, delivery, s.cfg.MaxFailures)
+	if err != nil && err != pgx.ErrNoRows {
+		s.logger.Sugar().Errorf("failed to record webhook(%d) delivery: %s", w.ID, err.Error())
+	}
+	if disabled {
+		s.logger.Sugar().Warnf("disabled webhook(%d) of user(%s) after %d consecutive failures", w.ID, w.UserID.String(), s.cfg.MaxFailures)
+	}
+
+	if sendErr == nil || disabled || err == pgx.ErrNoRows {
+		msg.Ack(false)
+		return
+	}
+
+	s.retryDelivery(msg, sendErr)
+}
+
+// retryDelivery schedules the delivery's retry with backoff, or dead-letters it if it can't be scheduled
+func (s *webhookService) retryDelivery(msg amqp.Delivery, cause error) {
+	if _, err := s.rabbitmq.Retry(rabbitmq.WEBHOOK_DELIVERY_QUEUE, msg, s.retryPolicy(), cause); err != nil {
+		s.logger.Sugar().Errorf("failed to schedule retry of message from queue(%s): %s", rabbitmq.WEBHOOK_DELIVERY_QUEUE, err.Error())
+		s.rabbitmq.Reject(rabbitmq.WEBHOOK_DELIVERY_QUEUE, msg, cause)
+		return
+	}
+
+	msg.Ack(false)
+}
+
+// webhookDelivery queues every notification for the webhooks its receiver subscribed to its type
+func (s *notificationService) webhookDelivery(msgs []model.NotificationDelivery) {
+	ctx, cancel := context.WithTimeout(context.Background(), PUSH_SEND_TIMEOUT)
+	defer cancel()
+
+	byType := make(map[string][]model.NotificationDelivery)
+	for _, msg := range msgs {
+		byType[msg.Type] = append(byType[msg.Type], msg)
+	}
+
+	for notificationType, typeMsgs := range byType {
+		webhooks, err := s.repo.Postgres.Webhook.GetSubscribed(ctx, receivers(typeMsgs), notificationType)
+		if err != nil {
+			s.logger.Sugar().Errorf("failed to get %d users' webhooks: %s", len(typeMsgs), err.Error())
+			continue
+		}
+		if len(webhooks) == 0 {
+			continue
+		}
+
+		userWebhooks := make(map[uuid.UUID][]*model.Webhook)
+		for _, w := range webhooks {
+			userWebhooks[w.UserID] = append(userWebhooks[w.UserID], w)
+		}
+
+		for _, msg := range typeMsgs {
+			if len(userWebhooks[msg.ReceiverID]) > 0 {
+				s.queueWebhookEvent(msg, userWebhooks[msg.ReceiverID])
+			}
+		}
+	}
+}
+
+// queueWebhookEvent queues the notification for each of the user's webhooks
+func (s *notificationService) queueWebhookEvent(msg model.NotificationDelivery, webhooks []*model.Webhook) {
+	// one event id for all of the user's webhooks, so receivers can deduplicate
+	event := webhookEvent{
+		ID: uuid.New(),
+		Type: msg.Type,
+		UserID: msg.ReceiverID,
+		Content: msg.Content,
+		ResourceID: msg.ResourceID,
+		CreatedAt: time.Now().UTC(),
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		s.logger.Sugar().Errorf("failed to marshal webhook event: %s", err.Error())
+		return
+	}
+
+	for _, w := range webhooks {
+		deliveryJSON, err := json.Marshal(dto.MQWebhookDelivery{
+			EventID: event.ID,
+			WebhookID: w.ID,
+			Type: msg.Type,
+			Payload: payload,
+		})
+		if err != nil {
+			s.logger.Sugar().Errorf("failed to marshal webhook delivery: %s", err.Error())
+			return
+		}
+
+		if err := s.rabbitmq.PublishToQueue(rabbitmq.WEBHOOK_DELIVERY_QUEUE, deliveryJSON); err != nil {
+			s.logger.Sugar().Errorf("failed to queue webhook(%d) delivery: %s", w.ID, err.Error())
+		}
+	}
+}
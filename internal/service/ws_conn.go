@@ -0,0 +1,51 @@
+package service
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/gorilla/websocket"
+)
+
+const WS_WRITE_TIMEOUT = time.Second * 10
+
+// wsConn serializes writes to a websocket connection,
+// since gorilla's conn supports only one concurrent writer.
+type wsConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+	idle atomic.Bool // reported by the client
+}
+
+func newWSConn(conn *websocket.Conn) *wsConn {
+	return &wsConn{conn: conn}
+}
+
+func (c *wsConn) writeJSON(v interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(WS_WRITE_TIMEOUT))
+	return c.conn.WriteJSON(v)
+}
+
+func (c *wsConn) writePreparedMessage(pm *websocket.PreparedMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(WS_WRITE_TIMEOUT))
+	return c.conn.WritePreparedMessage(pm)
+}
+
+func (c *wsConn) close() error {
+	return c.conn.Close()
+}
+
+func (c *wsConn) presenceState() string {
+	if c.idle.Load() {
+		return model.PRESENCE_IDLE
+	}
+	return model.PRESENCE_ONLINE
+}
@@ -0,0 +1,21 @@
+package sms
+
+import (
+	"errors"
+	"regexp"
+)
+
+var (
+	ErrInvalidPhone = errors.New("phone number must be in E.164 format, e.g. +14155550100")
+	ErrRateLimited = errors.New("sms rate limit exceeded for the number")
+)
+
+var e164Regexp = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// ValidatePhone checks that the number is in E.164 format
+func ValidatePhone(phone string) error {
+	if !e164Regexp.MatchString(phone) {
+		return ErrInvalidPhone
+	}
+	return nil
+}
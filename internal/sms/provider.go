@@ -0,0 +1,31 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BloggingApp/notification-service/internal/config"
+	"go.uber.org/zap"
+)
+
+const (
+	PROVIDER_HTTP = "http"
+	PROVIDER_FAKE = "fake"
+)
+
+// Provider hands a text message over to an SMS gateway and returns the gateway's message ID,
+// which its delivery status callbacks refer to
+type Provider interface {
+	Send(ctx context.Context, to string, body string) (string, error)
+}
+
+func NewProvider(logger *zap.Logger, cfg config.SMSConfig, callbackToken string) (Provider, error) {
+	switch cfg.Provider {
+	case PROVIDER_HTTP, "":
+		return NewHTTPProvider(cfg.HTTP, cfg.StatusCallbackURL, callbackToken), nil
+	case PROVIDER_FAKE:
+		return NewFakeProvider(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown sms provider: %s", cfg.Provider)
+	}
+}
@@ -0,0 +1,47 @@
+package sms
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type RecordedSMS struct {
+	ID   string
+	To   string
+	Body string
+}
+
+// FakeProvider keeps messages in memory instead of sending them.
+// It's meant for local development and tests. Bodies hold sign-in codes, so only ids and recipients are logged.
+type FakeProvider struct {
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	messages []RecordedSMS
+}
+
+func NewFakeProvider(logger *zap.Logger) *FakeProvider {
+	return &FakeProvider{logger: logger}
+}
+
+func (p *FakeProvider) Send(ctx context.Context, to string, body string) (string, error) {
+	id := uuid.NewString()
+
+	p.mu.Lock()
+	p.messages = append(p.messages, RecordedSMS{ID: id, To: to, Body: body})
+	p.mu.Unlock()
+
+	p.logger.Info("sms sent to fake provider", zap.String("id", id), zap.String("to", to))
+
+	return id, nil
+}
+
+func (p *FakeProvider) Messages() []RecordedSMS {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]RecordedSMS(nil), p.messages...)
+}
@@ -0,0 +1,92 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/BloggingApp/notification-service/internal/config"
+	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+)
+
+// HTTPProvider posts messages as JSON to a generic gateway:
+//
+//	POST <url>
+//	Authorization: Bearer <token>
+//	{"from": "...", "to": "+15550100", "body": "...", "status_callback": "..."}
+//
+// and expects {"id": "..."} back. The gateway posts delivery statuses to status_callback.
+type HTTPProvider struct {
+	httpClient     *http.Client
+	cfg            config.SMSHTTPConfig
+	statusCallback string
+}
+
+func NewHTTPProvider(cfg config.SMSHTTPConfig, statusCallbackURL string, callbackToken string) *HTTPProvider {
+	statusCallback := ""
+	if statusCallbackURL != "" {
+		// gateways can rarely add headers to callbacks, so the token rides in the url
+		statusCallback = statusCallbackURL + "?" + url.Values{"token": {callbackToken}}.Encode()
+	}
+
+	return &HTTPProvider{
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		cfg: cfg,
+		statusCallback: statusCallback,
+	}
+}
+
+type httpProviderRequest struct {
+	From           string `json:"from"`
+	To             string `json:"to"`
+	Body           string `json:"body"`
+	StatusCallback string `json:"status_callback,omitempty"`
+}
+
+func (p *HTTPProvider) Send(ctx context.Context, to string, body string) (string, error) {
+	reqBody, err := json.Marshal(httpProviderRequest{
+		From: p.cfg.From,
+		To: to,
+		Body: body,
+		StatusCallback: p.statusCallback,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.URL, bytes.NewReader(reqBody))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.cfg.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("sms gateway responded with %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+		// the gateway refused the message itself, sending it again won't help
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusRequestTimeout {
+			return "", rabbitmq.Permanent(err)
+		}
+		return "", err
+	}
+
+	var accepted struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(respBody, &accepted); err != nil {
+		return "", fmt.Errorf("failed to parse sms gateway response: %w", err)
+	}
+
+	return accepted.ID, nil
+}
@@ -0,0 +1,180 @@
+package sms
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/config"
+	"github.com/BloggingApp/notification-service/internal/dto"
+	"github.com/BloggingApp/notification-service/internal/model"
+	"github.com/BloggingApp/notification-service/internal/rabbitmq"
+	"github.com/BloggingApp/notification-service/internal/repository"
+	"github.com/BloggingApp/notification-service/internal/repository/redisrepo"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	SIGNIN_CODE_TYPE = "signin_code"
+
+	DEFAULT_RETRY_MAX_ATTEMPTS = 3 // codes expire quickly, a late one is useless
+	DEFAULT_RETRY_INITIAL_DELAY = time.Second * 5
+	SEND_TIMEOUT = time.Second * 15
+)
+
+// Sender consumes the SMS queues and sends the messages through the provider
+type Sender struct {
+	logger *zap.Logger
+	rabbitmq *rabbitmq.MQConn
+	rdb *redis.Client
+	provider Provider
+	repo *repository.Repository
+	cfg config.SMSConfig
+	callbackToken string
+}
+
+func New(logger *zap.Logger, rabbitmq *rabbitmq.MQConn, rdb *redis.Client, provider Provider, repo *repository.Repository, callbackToken string, cfg config.SMSConfig) *Sender {
+	return &Sender{
+		logger: logger,
+		rabbitmq: rabbitmq,
+		rdb: rdb,
+		provider: provider,
+		repo: repo,
+		cfg: cfg,
+		callbackToken: callbackToken,
+	}
+}
+
+// CheckCallbackToken tells whether a status callback comes from the gateway
+func (s *Sender) CheckCallbackToken(token string) bool {
+	return s.callbackToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.callbackToken)) == 1
+}
+
+func (s *Sender) retryPolicy() rabbitmq.RetryPolicy {
+	policy := rabbitmq.RetryPolicy{
+		MaxAttempts: s.cfg.RetryMaxAttempts,
+		InitialDelay: s.cfg.RetryInitialDelay,
+	}
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = DEFAULT_RETRY_MAX_ATTEMPTS
+	}
+	if policy.InitialDelay <= 0 {
+		policy.InitialDelay = DEFAULT_RETRY_INITIAL_DELAY
+	}
+
+	return policy
+}
+
+func (s *Sender) StartProcessing() {
+	go s.ProcessSignInCodes()
+}
+
+func (s *Sender) ProcessSignInCodes() {
+	queue := rabbitmq.SIGNIN_CODE_SMS_QUEUE
+	worker := s.rabbitmq.NewWorker(queue, s.retryPolicy(), nil)
+	worker.Run(s.cfg.Workers, s.cfg.Workers, func(msg amqp.Delivery) {
+		var input dto.MQSignInCodeSMS
+		if err := json.Unmarshal(msg.Body, &input); err != nil {
+			s.logger.Sugar().Errorf("Failed to decode message in queue(%s): %s", queue, err.Error())
+			worker.Fail(msg, rabbitmq.Permanent(err))
+			return
+		}
+
+		if err := ValidatePhone(input.Phone); err != nil {
+			s.logger.Sugar().Errorf("Failed to send sms from queue(%s): %s", queue, err.Error())
+			s.record(SIGNIN_CODE_TYPE, input.Phone, "", rabbitmq.Attempt(msg), rabbitmq.Permanent(err))
+			worker.Fail(msg, rabbitmq.Permanent(err))
+			return
+		}
+
+		if retryAfter, err := s.checkNumberLimit(context.Background(), input.Phone); err != nil {
+			s.dropRateLimited(SIGNIN_CODE_TYPE, input.Phone, rabbitmq.Attempt(msg), err, retryAfter)
+			msg.Ack(false)
+			return
+		}
+
+		body := fmt.Sprintf("%s: your sign-in code is %d. Don't share it with anyone.", s.cfg.AppName, input.Code)
+
+		ctx, cancel := context.WithTimeout(context.Background(), SEND_TIMEOUT)
+		messageID, err := s.provider.Send(ctx, input.Phone, body)
+		cancel()
+		s.record(SIGNIN_CODE_TYPE, input.Phone, messageID, rabbitmq.Attempt(msg), err)
+		if err != nil {
+			s.logger.Sugar().Errorf("Failed to send sms to(%s): %s", input.Phone, err.Error())
+			worker.Fail(msg, err)
+			return
+		}
+
+		s.logger.Sugar().Infof("Successfully sent %s sms from queue(%s) to(%s)", SIGNIN_CODE_TYPE, queue, input.Phone)
+		msg.Ack(false)
+	})
+}
+
+// checkNumberLimit counts a message in the number's current window and fails once the window is full,
+// returning when the window ends. Like mail limits it fails open when Redis is unavailable.
+func (s *Sender) checkNumberLimit(ctx context.Context, phone string) (time.Time, error) {
+	limit := s.cfg.NumberLimit
+	exceeded, windowEnd, err := redisrepo.HitLimit(s.rdb, ctx, func(windowStart int64) string {
+		return redisrepo.SMSLimitKey(phone, windowStart)
+	}, limit.Max, limit.Window)
+	if err != nil {
+		s.logger.Sugar().Errorf("Failed to check sms limit: %s", err.Error())
+	}
+	if exceeded {
+		return windowEnd, fmt.Errorf("%w: %d per %s", ErrRateLimited, limit.Max, limit.Window)
+	}
+
+	return time.Time{}, nil
+}
+
+// dropRateLimited records the dropped sms and tells the producer about it
+func (s *Sender) dropRateLimited(typeName string, phone string, attempt int, rlErr error, retryAfter time.Time) {
+	s.logger.Sugar().Warnf("Dropped %s sms to(%s): %s", typeName, phone, rlErr.Error())
+	s.record(typeName, phone, "", attempt, rlErr)
+
+	eventJSON, err := json.Marshal(dto.MQSMSRateLimited{
+		Type: typeName,
+		Phone: phone,
+		RetryAfter: retryAfter,
+	})
+	if err != nil {
+		s.logger.Sugar().Errorf("Failed to marshal rate limited event: %s", err.Error())
+		return
+	}
+
+	if err := s.rabbitmq.PublishToQueue(rabbitmq.SMS_RATE_LIMITED_QUEUE, eventJSON); err != nil {
+		s.logger.Sugar().Errorf("Failed to publish rate limited event for(%s): %s", phone, err.Error())
+	}
+}
+
+// record stores the outcome of a send attempt
+func (s *Sender) record(typeName string, phone string, messageID string, attempt int, sendErr error) {
+	msg := model.SMSMessage{
+		MessageID: messageID,
+		Type: typeName,
+		Phone: phone,
+		Status: model.SMS_STATUS_QUEUED,
+		Attempt: attempt,
+	}
+
+	if sendErr != nil {
+		switch {
+		case errors.Is(sendErr, ErrRateLimited):
+			msg.Status = model.SMS_STATUS_RATE_LIMITED
+		case rabbitmq.IsPermanent(sendErr):
+			msg.Status = model.SMS_STATUS_REJECTED
+		default:
+			msg.Status = model.SMS_STATUS_FAILED
+		}
+		msg.Detail = sendErr.Error()
+	}
+
+	if err := s.repo.Postgres.SMS.Create(context.Background(), msg); err != nil {
+		s.logger.Sugar().Errorf("Failed to record %s sms to(%s): %s", typeName, phone, err.Error())
+	}
+}
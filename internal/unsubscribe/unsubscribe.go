@@ -0,0 +1,101 @@
+package unsubscribe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MIN_SECRET_LENGTH matches the HMAC-SHA256 output, a shorter secret makes tokens guessable
+const MIN_SECRET_LENGTH = 32
+
+var (
+	ErrInvalidToken = errors.New("invalid unsubscribe token")
+	ErrTokenExpired = errors.New("unsubscribe token has expired")
+	ErrSecretTooShort = errors.New("unsubscribe secret must be at least 32 bytes")
+)
+
+// Claims is what an unsubscribe token grants: turning off one email category for one user
+type Claims struct {
+	UserID    uuid.UUID
+	Category  string
+	ExpiresAt time.Time
+}
+
+// Signer issues and verifies unsubscribe tokens. A token is the base64url encoded
+// "<user id>:<category>:<expiry unix>" payload and its HMAC-SHA256, separated by a dot.
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewSigner(secret []byte, ttl time.Duration) (*Signer, error) {
+	if len(secret) < MIN_SECRET_LENGTH {
+		return nil, ErrSecretTooShort
+	}
+
+	return &Signer{
+		secret: secret,
+		ttl: ttl,
+	}, nil
+}
+
+func (s *Signer) Sign(userID uuid.UUID, category string) string {
+	payload := userID.String() + ":" + category + ":" + strconv.FormatInt(time.Now().Add(s.ttl).Unix(), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + base64.RawURLEncoding.EncodeToString(s.mac([]byte(payload)))
+}
+
+func (s *Signer) Verify(token string) (*Claims, error) {
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal(mac, s.mac(payload)) {
+		return nil, ErrInvalidToken
+	}
+
+	parts := strings.Split(string(payload), ":")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	userID, err := uuid.Parse(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	exp, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims := &Claims{
+		UserID: userID,
+		Category: parts[1],
+		ExpiresAt: time.Unix(exp, 0),
+	}
+	if time.Now().After(claims.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	return claims, nil
+}
+
+func (s *Signer) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write(payload)
+	return h.Sum(nil)
+}
@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/netguard"
+)
+
+const (
+	ID_HEADER = "X-Webhook-Id"
+	TIMESTAMP_HEADER = "X-Webhook-Timestamp"
+	SIGNATURE_HEADER = "X-Webhook-Signature"
+
+	SIGNATURE_PREFIX = "sha256="
+	SECRET_SIZE = 32
+	// receivers should reject deliveries with a timestamp older than this to block replays
+	DEFAULT_TOLERANCE = time.Minute * 5
+)
+
+var (
+	ErrInvalidURL = errors.New("webhook url must be an absolute https url")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrTimestampExpired = errors.New("webhook timestamp is out of tolerance")
+)
+
+// NewSecret generates a signing secret for a new subscription
+func NewSecret() (string, error) {
+	b := make([]byte, SECRET_SIZE)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header value: the HMAC-SHA256 of "timestamp.body" keyed with the secret.
+// Signing the timestamp with the body keeps it from being swapped to replay an old delivery.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return SIGNATURE_PREFIX + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a delivery the way receivers are expected to
+func Verify(secret string, timestamp int64, body []byte, signature string, tolerance time.Duration) error {
+	if d := time.Since(time.Unix(timestamp, 0)); d > tolerance || d < -tolerance {
+		return ErrTimestampExpired
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" || len(rawURL) > 2048 {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+// Response is what the receiver answered with
+type Response struct {
+	StatusCode int
+	Body       string // the start of it, for the delivery log
+	Duration   time.Duration
+}
+
+type Client struct {
+	httpClient *http.Client
+}
+
+// NewClient returns a client that refuses to connect to loopback, private and link-local
+// addresses, so users can't point webhooks at the internal network
+func NewClient(timeout time.Duration) *Client {
+	return &Client{
+		httpClient: netguard.NewHTTPClient(timeout),
+	}
+}
+
+// Send posts the signed body to the url. Any response is returned, the caller decides
+// which status codes count as delivered.
+func (c *Client) Send(ctx context.Context, url string, secret string, eventID string, body []byte) (*Response, error) {
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BloggingApp-Webhooks/1.0")
+	req.Header.Set(ID_HEADER, eventID)
+	req.Header.Set(TIMESTAMP_HEADER, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SIGNATURE_HEADER, Sign(secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Body: string(respBody),
+		Duration: time.Since(start),
+	}, nil
+}
+
+func (r *Response) OK() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+func (r *Response) Err() error {
+	if r.OK() {
+		return nil
+	}
+	return fmt.Errorf("webhook responded with %d", r.StatusCode)
+}
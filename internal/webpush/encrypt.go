@@ -0,0 +1,114 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+const (
+	// a single record holds the whole payload, push services accept up to 4096 bytes
+	RECORD_SIZE = 4096
+	MAX_PAYLOAD_SIZE = RECORD_SIZE - 16 - 1 - 86 // minus the AEAD tag, the delimiter and the header
+
+	SALT_SIZE = 16
+)
+
+var ErrPayloadTooLarge = errors.New("push payload is too large")
+
+// Encrypt encrypts the payload for the subscription with the aes128gcm content coding (RFC 8188),
+// deriving the key as described in RFC 8291
+func Encrypt(sub Subscription, payload []byte) ([]byte, error) {
+	if len(payload) > MAX_PAYLOAD_SIZE {
+		return nil, ErrPayloadTooLarge
+	}
+
+	uaPublicBytes, err := decodeBase64(sub.Keys.P256dh)
+	if err != nil {
+		return nil, errors.New("invalid p256dh key")
+	}
+	authSecret, err := decodeBase64(sub.Keys.Auth)
+	if err != nil || len(authSecret) != 16 {
+		return nil, errors.New("invalid auth secret")
+	}
+
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, errors.New("invalid p256dh key")
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, SALT_SIZE)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	// IKM = HKDF(auth_secret, ecdh_secret, "WebPush: info" || 0x00 || ua_public || as_public, 32)
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublicBytes...)
+	keyInfo = append(keyInfo, asPublicBytes...)
+	ikm := hkdfExpand(hkdfExtract(authSecret, ecdhSecret), keyInfo, 32)
+
+	prk := hkdfExtract(salt, ikm)
+	cek := hkdfExpand(prk, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce := hkdfExpand(prk, []byte("Content-Encoding: nonce\x00"), 12)
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 0x02 marks the last record, no padding follows
+	plaintext := append(append([]byte{}, payload...), 0x02)
+
+	header := make([]byte, 0, SALT_SIZE+4+1+len(asPublicBytes))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, RECORD_SIZE)
+	header = append(header, byte(len(asPublicBytes)))
+	header = append(header, asPublicBytes...)
+
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+func hkdfExtract(salt, ikm []byte) []byte {
+	h := hmac.New(sha256.New, salt)
+	h.Write(ikm)
+	return h.Sum(nil)
+}
+
+// hkdfExpand only needs a single block, every key here is at most 32 bytes long
+func hkdfExpand(prk, info []byte, length int) []byte {
+	h := hmac.New(sha256.New, prk)
+	h.Write(info)
+	h.Write([]byte{0x01})
+	return h.Sum(nil)[:length]
+}
+
+// decodeBase64 accepts the URL-safe alphabet browsers use, with or without padding
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
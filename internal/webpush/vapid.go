@@ -0,0 +1,88 @@
+package webpush
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const VAPID_TOKEN_TTL = time.Hour * 12
+
+// VAPID identifies this server to push services (RFC 8292)
+type VAPID struct {
+	privateKey *ecdsa.PrivateKey
+	publicKey  string // uncompressed P-256 point, base64url, also the browsers' applicationServerKey
+	subject    string // mailto: or https: contact of the sender
+}
+
+// NewVAPID loads the key pair from the base64url encoded private key scalar
+func NewVAPID(privateKey string, subject string) (*VAPID, error) {
+	d, err := decodeBase64(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+
+	key, err := ecdh.P256().NewPrivateKey(d)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vapid private key: %w", err)
+	}
+	if subject == "" {
+		return nil, errors.New("vapid subject is required")
+	}
+
+	public := key.PublicKey().Bytes()
+	return &VAPID{
+		privateKey: &ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: elliptic.P256(),
+				X: new(big.Int).SetBytes(public[1:33]),
+				Y: new(big.Int).SetBytes(public[33:]),
+			},
+			D: new(big.Int).SetBytes(d),
+		},
+		publicKey: base64.RawURLEncoding.EncodeToString(public),
+		subject: subject,
+	}, nil
+}
+
+// GenerateVAPIDKeys creates a new base64url encoded key pair
+func GenerateVAPIDKeys() (privateKey string, publicKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(key.Bytes()), base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+func (v *VAPID) PublicKey() string {
+	return v.publicKey
+}
+
+// authorization returns the Authorization header for a request to the push endpoint
+func (v *VAPID) authorization(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": u.Scheme + "://" + u.Host,
+		"exp": time.Now().Add(VAPID_TOKEN_TTL).Unix(),
+		"sub": v.subject,
+	}).SignedString(v.privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	return "vapid t=" + token + ", k=" + v.publicKey, nil
+}
@@ -0,0 +1,144 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BloggingApp/notification-service/internal/netguard"
+)
+
+const (
+	URGENCY_VERY_LOW = "very-low"
+	URGENCY_LOW = "low"
+	URGENCY_NORMAL = "normal"
+	URGENCY_HIGH = "high"
+
+	DEFAULT_TTL = time.Hour * 24
+)
+
+// ErrSubscriptionGone means the push service doesn't know the subscription anymore, it should be removed
+var ErrSubscriptionGone = errors.New("push subscription has expired or was unsubscribed")
+
+// Subscription is what the browser's PushSubscription.toJSON() returns
+type Subscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     Keys   `json:"keys"`
+}
+
+type Keys struct {
+	P256dh string `json:"p256dh"`
+	Auth   string `json:"auth"`
+}
+
+type Options struct {
+	TTL     time.Duration // how long the push service keeps the message for an offline browser
+	Urgency string
+	Topic   string // a newer message with the same topic replaces an undelivered one
+}
+
+// Client sends encrypted messages to push services
+type Client struct {
+	httpClient *http.Client
+	vapid      *VAPID
+}
+
+// NewClient sends with the http client. Subscriptions come from users, so outside of tests
+// it should be a netguard client, which refuses private addresses and redirects.
+func NewClient(httpClient *http.Client, vapid *VAPID) *Client {
+	return &Client{
+		httpClient: httpClient,
+		vapid: vapid,
+	}
+}
+
+func (c *Client) VAPIDPublicKey() string {
+	return c.vapid.PublicKey()
+}
+
+func (c *Client) Send(ctx context.Context, sub Subscription, payload []byte, opts Options) error {
+	body, err := Encrypt(sub, payload)
+	if err != nil {
+		return err
+	}
+
+	authorization, err := c.vapid.authorization(sub.Endpoint)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = DEFAULT_TTL
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", strconv.Itoa(int(ttl.Seconds())))
+	if opts.Urgency != "" {
+		req.Header.Set("Urgency", opts.Urgency)
+	}
+	if opts.Topic != "" {
+		req.Header.Set("Topic", opts.Topic)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrSubscriptionGone
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("push service responded with %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+}
+
+// Validate checks that the subscription can be encrypted to and points at an HTTPS push service
+// on a public host. The client's dialer still has to check where the host resolves to.
+func (s Subscription) Validate() error {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil || len(s.Endpoint) > 2048 {
+		return errors.New("endpoint must be an https URL")
+	}
+	if host := u.Hostname(); host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.New("endpoint must be a public host")
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !netguard.IsPublic(ip) {
+		return errors.New("endpoint must be a public host")
+	}
+
+	p256dh, err := decodeBase64(s.Keys.P256dh)
+	if err != nil {
+		return errors.New("invalid p256dh key")
+	}
+	if _, err := ecdh.P256().NewPublicKey(p256dh); err != nil {
+		return errors.New("invalid p256dh key")
+	}
+
+	auth, err := decodeBase64(s.Keys.Auth)
+	if err != nil || len(auth) != 16 {
+		return errors.New("invalid auth secret")
+	}
+
+	return nil
+}